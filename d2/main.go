@@ -2,101 +2,618 @@ package main
 
 import (
 	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"math/big"
 	"os"
-	"strconv"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
+// IdRange holds ids as big.Int so serial numbers longer than an int64 are still summed exactly
 type IdRange struct {
-	start           int
-	end             int
-	invalid         []int
-	invalidMultiple []int
+	start *big.Int
+	end   *big.Int
+	// totals holds the invalid count and sum for each selected rule, in the same order as rules
+	totals []ruleTotal
 }
 
-var ranges []*IdRange
+type ruleTotal struct {
+	count *big.Int
+	sum   *big.Int
+}
+
+// Rule decides whether an id is invalid.
+// Every rule can check a single id, rules that can also total a whole range without
+// checking each id (see rangeSummer) are used that way by calcInvalid.
+type Rule interface {
+	Name() string
+	Invalid(id string) bool
+}
+
+// rangeSummer is implemented by rules that can work out the invalid count and sum of a range directly
+type rangeSummer interface {
+	rangeSum(r *IdRange) (*big.Int, *big.Int)
+}
+
+// idGenerator is implemented by rules that can list the invalid ids of a range without checking every id
+type idGenerator interface {
+	rangeIDs(r *IdRange) []*big.Int
+}
+
+// ExactRepeatRule marks ids made of a block repeated exactly Times times, Times 2 being "first half equals second half"
+type ExactRepeatRule struct {
+	Times int
+}
+
+// AtLeastTwiceRule marks ids made of a block repeated two or more times
+type AtLeastTwiceRule struct{}
+
+// PalindromeRule marks ids that read the same in both directions
+type PalindromeRule struct{}
+
+// RegexRule marks ids matching a user supplied regular expression
+type RegexRule struct {
+	Pattern *regexp.Regexp
+}
+
+var (
+	ranges []*IdRange
+	rules  []Rule
+	one    = big.NewInt(1)
+
+	ruleNames  = flag.String("rules", "twice,repeated", "comma separated rules to apply: twice, repeated, times, palindrome, regex")
+	ruleTimes  = flag.Int("times", 3, "number of repeats used by the times rule")
+	ruleRegexp = flag.String("regex", "", "regular expression used by the regex rule")
+	overlaps   = flag.Bool("overlaps", false, "report input ranges that overlap and the double counting they would cause")
+	report     = flag.String("report", "", "write a per range report in input order instead of the sums: csv or json")
+	reportIDs  = flag.Bool("ids", false, "include the invalid ids themselves in the report")
+	base       = flag.Int("base", 10, "base (2 to 36) the ids are written in, sums are always reported in decimal")
+
+	// partLabels keeps the original output for the default rules, rule names are only used once -rules is given
+	partLabels = []string{"PartOne", "PartTwo"}
+)
 
 func init() {
+	flag.Parse()
+	if *base < 2 || *base > 36 {
+		fmt.Fprintf(os.Stderr, "base must be between 2 and 36, got %d\n", *base)
+		os.Exit(1)
+	}
+	for _, name := range strings.Split(*ruleNames, ",") {
+		rule, err := parseRule(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		rules = append(rules, rule)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "rules" {
+			partLabels = nil
+		}
+	})
+
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
 		for _, seq := range strings.Split(scanner.Text(), ",") {
-			parts := strings.Split(seq, "-")
-			ranges = append(ranges, &IdRange{
-				start:           aToIIgnoreError(parts[0]),
-				end:             aToIIgnoreError(parts[1]),
-				invalid:         []int{},
-				invalidMultiple: []int{},
-			})
+			seq = strings.TrimSpace(seq)
+			if seq == "" {
+				continue
+			}
+			r, err := parseRange(seq)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			ranges = append(ranges, r)
 		}
 	}
 }
 
+func parseRule(name string) (Rule, error) {
+	switch strings.TrimSpace(name) {
+	case "twice":
+		return ExactRepeatRule{Times: 2}, nil
+	case "repeated":
+		return AtLeastTwiceRule{}, nil
+	case "times":
+		if *ruleTimes < 2 {
+			return nil, fmt.Errorf("times rule needs at least 2 repeats, got %d", *ruleTimes)
+		}
+		return ExactRepeatRule{Times: *ruleTimes}, nil
+	case "palindrome":
+		return PalindromeRule{}, nil
+	case "regex":
+		pattern, err := regexp.Compile(*ruleRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex rule: %w", err)
+		}
+		return RegexRule{Pattern: pattern}, nil
+	}
+	return nil, fmt.Errorf("unknown rule %q", name)
+}
+
+// rangeReport is one row of the per range report, rules are in the order they were selected.
+// big.Int marshals to a plain JSON number so long ids are written exactly.
+type rangeReport struct {
+	Start *big.Int     `json:"start"`
+	End   *big.Int     `json:"end"`
+	Rules []ruleReport `json:"rules"`
+}
+
+type ruleReport struct {
+	Rule  string     `json:"rule"`
+	Count *big.Int   `json:"count"`
+	Sum   *big.Int   `json:"sum"`
+	IDs   []*big.Int `json:"ids,omitempty"`
+}
+
 func (i *IdRange) String() string {
-	return fmt.Sprintf("%d to %d has %d invalid: %v", i.start, i.end, len(i.invalid), i.invalid)
+	parts := []string{}
+	for idx, total := range i.totals {
+		parts = append(parts, fmt.Sprintf("%s: %s invalid (sum %s)", rules[idx].Name(), total.count, total.sum))
+	}
+	return fmt.Sprintf("%s to %s has %s", i.start, i.end, strings.Join(parts, ", "))
+}
+
+// parseRange reads a "start-end" range such as "11-22"
+func parseRange(seq string) (*IdRange, error) {
+	startID, endID, found := strings.Cut(seq, "-")
+	if !found {
+		return nil, fmt.Errorf("range %q: expected start-end", seq)
+	}
+	start, err := parseID(startID)
+	if err != nil {
+		return nil, fmt.Errorf("range %q: %w", seq, err)
+	}
+	end, err := parseID(endID)
+	if err != nil {
+		return nil, fmt.Errorf("range %q: %w", seq, err)
+	}
+	if start.Cmp(end) > 0 {
+		return nil, fmt.Errorf("range %q: start is after end", seq)
+	}
+	return &IdRange{start: start, end: end}, nil
 }
 
-func aToIIgnoreError(s string) int {
-	result, _ := strconv.Atoi(s)
-	return result
+// parseID reads an id of any length written in the selected base, e.g. "ff" is 255 in base 16
+func parseID(s string) (*big.Int, error) {
+	result, ok := new(big.Int).SetString(strings.ToLower(s), *base)
+	if !ok || strings.HasPrefix(s, "+") {
+		return nil, fmt.Errorf("%q is not an id in base %d", s, *base)
+	}
+	return result, nil
 }
 
-func calcInvalid() (int, int) {
-	partOne := 0
-	partTwo := 0
-	for _, r := range ranges {
-		for candidate := r.start; candidate <= r.end; candidate++ {
-			candidateAsString := strconv.Itoa(candidate)
-			if checkRepeatedInvalid(candidateAsString) {
-				r.invalidMultiple = append(r.invalidMultiple, candidate)
-				partTwo += candidate
+// formatID writes an id in the selected base so the rules check its digits in that base
+func formatID(n *big.Int) string {
+	return n.Text(*base)
+}
+
+func (r ExactRepeatRule) Name() string {
+	if r.Times == 2 {
+		return "exactly-twice"
+	}
+	return fmt.Sprintf("exactly-%d-times", r.Times)
+}
+
+func (r ExactRepeatRule) Invalid(id string) bool {
+	return len(id)%r.Times == 0 && checkRepeatedBlock(id, len(id)/r.Times)
+}
+
+func (r ExactRepeatRule) rangeSum(i *IdRange) (*big.Int, *big.Int) {
+	count := new(big.Int)
+	sum := new(big.Int)
+	for idLen := numDigits(i.start); idLen <= numDigits(i.end); idLen++ {
+		if idLen%r.Times == 0 {
+			c, s := i.repeatedSum(idLen, idLen/r.Times)
+			count.Add(count, c)
+			sum.Add(sum, s)
+		}
+	}
+	return count, sum
+}
+
+func (r ExactRepeatRule) rangeIDs(i *IdRange) []*big.Int {
+	ids := []*big.Int{}
+	for idLen := numDigits(i.start); idLen <= numDigits(i.end); idLen++ {
+		if idLen%r.Times == 0 {
+			ids = append(ids, i.repeatedIDs(idLen, idLen/r.Times)...)
+		}
+	}
+	return ids
+}
+
+func (AtLeastTwiceRule) Name() string {
+	return "at-least-twice"
+}
+
+func (AtLeastTwiceRule) Invalid(id string) bool {
+	return checkRepeatedInvalid(id)
+}
+
+func (AtLeastTwiceRule) rangeSum(i *IdRange) (*big.Int, *big.Int) {
+	count := new(big.Int)
+	sum := new(big.Int)
+	for idLen := numDigits(i.start); idLen <= numDigits(i.end); idLen++ {
+		c, s := i.anyRepeatedSum(idLen)
+		count.Add(count, c)
+		sum.Add(sum, s)
+	}
+	return count, sum
+}
+
+func (AtLeastTwiceRule) rangeIDs(i *IdRange) []*big.Int {
+	ids := []*big.Int{}
+	for idLen := numDigits(i.start); idLen <= numDigits(i.end); idLen++ {
+		for blockLen := 1; blockLen <= idLen/2; blockLen++ {
+			if idLen%blockLen == 0 {
+				ids = append(ids, i.repeatedIDs(idLen, blockLen)...)
 			}
-			if len(candidateAsString)%2 == 0 {
-				mid := len(candidateAsString) / 2
-				firstHalf := candidateAsString[:mid]
-				secondHalf := candidateAsString[mid:]
-				if firstHalf == secondHalf {
-					r.invalid = append(r.invalid, candidate)
-					partOne += candidate
-				}
+		}
+	}
+	// ids like 222222 are found once per block length that divides them
+	slices.SortFunc(ids, (*big.Int).Cmp)
+	return slices.CompactFunc(ids, func(a, b *big.Int) bool {
+		return a.Cmp(b) == 0
+	})
+}
+
+func (PalindromeRule) Name() string {
+	return "palindrome"
+}
+
+func (PalindromeRule) Invalid(id string) bool {
+	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+		if id[i] != id[j] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r RegexRule) Name() string {
+	return fmt.Sprintf("regex(%s)", r.Pattern)
+}
+
+func (r RegexRule) Invalid(id string) bool {
+	return r.Pattern.MatchString(id)
+}
+
+// mergeRanges sorts the ranges and merges any that overlap or touch so no id is counted twice
+// for example:
+// [11-22], [15-30], [31-40], [50-60]
+// becomes
+// [11-40], [50-60]
+func mergeRanges(input []*IdRange) []*IdRange {
+	if len(input) == 0 {
+		return nil
+	}
+	sorted := sortedByStart(input)
+
+	merged := []*IdRange{}
+	current := &IdRange{start: sorted[0].start, end: sorted[0].end}
+	for _, next := range sorted[1:] {
+		if next.start.Cmp(new(big.Int).Add(current.end, one)) <= 0 {
+			current.end = bigMax(current.end, next.end)
+		} else {
+			merged = append(merged, current)
+			current = &IdRange{start: next.start, end: next.end}
+		}
+	}
+	return append(merged, current)
+}
+
+func sortedByStart(input []*IdRange) []*IdRange {
+	sorted := make([]*IdRange, len(input))
+	copy(sorted, input)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start.Cmp(sorted[j].start) < 0
+	})
+	return sorted
+}
+
+// reportOverlaps prints every pair of input ranges that share ids, and how much each rule's sum
+// would have been inflated had the raw ranges been summed without merging.
+func reportOverlaps(rules []Rule, mergedSums []*big.Int) {
+	sorted := sortedByStart(ranges)
+
+	overlapCount := 0
+	for i, a := range sorted {
+		// sorted by start so once b starts after a ends no later range can overlap a either
+		for _, b := range sorted[i+1:] {
+			if b.start.Cmp(a.end) > 0 {
+				break
 			}
+			shared := new(big.Int).Sub(bigMin(a.end, b.end), b.start)
+			shared.Add(shared, one)
+			fmt.Printf("%s-%s overlaps %s-%s sharing %s ids\n", a.start, a.end, b.start, b.end, shared)
+			overlapCount++
 		}
 	}
-	return partOne, partTwo
+	fmt.Printf("%d overlapping pairs\n", overlapCount)
+
+	rawSums := calcInvalid(ranges, rules)
+	for idx, rule := range rules {
+		fmt.Printf("%s double counted: %s\n", rule.Name(), new(big.Int).Sub(rawSums[idx], mergedSums[idx]))
+	}
 }
 
-func checkRepeatedInvalid(id string) bool {
-	idLength := len(id)
+// calcInvalid totals every range under each rule and returns the sum of invalid ids per rule.
+// Rules that can sum a range directly do so, the rest fall back to checking every id in the range.
+func calcInvalid(idRanges []*IdRange, rules []Rule) []*big.Int {
+	sums := make([]*big.Int, len(rules))
+	for idx := range sums {
+		sums[idx] = new(big.Int)
+	}
+	for _, r := range idRanges {
+		r.totals = make([]ruleTotal, len(rules))
+		for idx, rule := range rules {
+			var count, sum *big.Int
+			if summer, ok := rule.(rangeSummer); ok {
+				count, sum = summer.rangeSum(r)
+			} else {
+				count, sum = r.checkEachSum(rule)
+			}
+			r.totals[idx] = ruleTotal{count: count, sum: sum}
+			sums[idx].Add(sums[idx], sum)
+		}
+	}
+	return sums
+}
+
+// checkEachSum walks every id in the range, only used for rules without a shortcut
+func (i *IdRange) checkEachSum(rule Rule) (*big.Int, *big.Int) {
+	count := new(big.Int)
+	sum := new(big.Int)
+	for candidate := new(big.Int).Set(i.start); candidate.Cmp(i.end) <= 0; candidate.Add(candidate, one) {
+		if rule.Invalid(formatID(candidate)) {
+			count.Add(count, one)
+			sum.Add(sum, candidate)
+		}
+	}
+	return count, sum
+}
+
+// invalidIDs lists the ids in the range the rule marks invalid in ascending order
+func (i *IdRange) invalidIDs(rule Rule) []*big.Int {
+	if generator, ok := rule.(idGenerator); ok {
+		return generator.rangeIDs(i)
+	}
+	ids := []*big.Int{}
+	for candidate := new(big.Int).Set(i.start); candidate.Cmp(i.end) <= 0; candidate.Add(candidate, one) {
+		if rule.Invalid(formatID(candidate)) {
+			ids = append(ids, new(big.Int).Set(candidate))
+		}
+	}
+	return ids
+}
+
+// anyRepeatedSum returns the count and sum of idLen digit ids in the range made of any block repeated at least twice.
+// An id such as 222222 repeats with block lengths 1, 2 and 3 so simply adding repeatedSum for each block length
+// would count it three times. Instead work out the ids whose smallest block is exactly blockLen by removing
+// those already found for every smaller block length that divides it (inclusion-exclusion), for example:
+// block 2 for length 6 finds 121212 and 222222 but 222222 was already found by block 1 so it is removed
+func (i *IdRange) anyRepeatedSum(idLen int) (*big.Int, *big.Int) {
+	exactCount := map[int]*big.Int{}
+	exactSum := map[int]*big.Int{}
+	totalCount := new(big.Int)
+	totalSum := new(big.Int)
+	for blockLen := 1; blockLen <= idLen/2; blockLen++ {
+		if idLen%blockLen != 0 {
+			continue
+		}
+		count, sum := i.repeatedSum(idLen, blockLen)
+		for smaller := 1; smaller < blockLen; smaller++ {
+			if blockLen%smaller == 0 {
+				count.Sub(count, exactCount[smaller])
+				sum.Sub(sum, exactSum[smaller])
+			}
+		}
+		exactCount[blockLen] = count
+		exactSum[blockLen] = sum
+		totalCount.Add(totalCount, count)
+		totalSum.Add(totalSum, sum)
+	}
+	return totalCount, totalSum
+}
+
+// repeatedSum returns the count and sum of idLen digit ids in the range made of a blockLen digit block repeated.
+// An id made of a block repeated is the block multiplied by a 1010...101 style multiplier, e.g. 121212 = 12 * 10101,
+// which holds in any base as long as the multiplier's digits are read in that base too.
+// The blocks are the blockLen digit numbers (e.g. 10 to 99 in decimal) so the ids are block * multiplier for consecutive
+// blocks, meaning we only need the first and last block inside the range to get the sum as an arithmetic series.
+func (i *IdRange) repeatedSum(idLen, blockLen int) (*big.Int, *big.Int) {
+	multiplier := repeatMultiplier(blockLen, idLen/blockLen)
+	lowBlock, highBlock := i.blockBounds(blockLen, multiplier)
+	if lowBlock.Cmp(highBlock) > 0 {
+		return new(big.Int), new(big.Int)
+	}
+	count := new(big.Int).Sub(highBlock, lowBlock)
+	count.Add(count, one)
+	// one of count or (lowBlock + highBlock) is always even so the halving is exact
+	series := new(big.Int).Add(lowBlock, highBlock)
+	series.Mul(series, count)
+	series.Rsh(series, 1)
+	return count, series.Mul(series, multiplier)
+}
+
+// repeatedIDs lists the same ids repeatedSum totals, smallest first
+func (i *IdRange) repeatedIDs(idLen, blockLen int) []*big.Int {
+	multiplier := repeatMultiplier(blockLen, idLen/blockLen)
+	lowBlock, highBlock := i.blockBounds(blockLen, multiplier)
+	ids := []*big.Int{}
+	for block := lowBlock; block.Cmp(highBlock) <= 0; block.Add(block, one) {
+		ids = append(ids, new(big.Int).Mul(block, multiplier))
+	}
+	return ids
+}
+
+// blockBounds returns the smallest and largest blockLen digit blocks whose repeat (block * multiplier) is inside the range
+func (i *IdRange) blockBounds(blockLen int, multiplier *big.Int) (*big.Int, *big.Int) {
+	// round the start up so the first block's repeat isn't below the range
+	ceiling := new(big.Int).Add(i.start, multiplier)
+	ceiling.Sub(ceiling, one)
+	ceiling.Quo(ceiling, multiplier)
+	lowBlock := bigMax(basePow(blockLen-1), ceiling)
+
+	largestBlock := basePow(blockLen)
+	largestBlock.Sub(largestBlock, one)
+	highBlock := bigMin(largestBlock, new(big.Int).Quo(i.end, multiplier))
+	return new(big.Int).Set(lowBlock), highBlock
+}
+
+// repeatMultiplier returns the number that repeats a blockLen digit block times times when multiplied by it
+// e.g. blockLen 2 and times 3 gives 10101 as 12 * 10101 = 121212 in decimal, in hex 10101 is 0x10101 = 65793
+func repeatMultiplier(blockLen, times int) *big.Int {
+	shift := basePow(blockLen)
+	multiplier := new(big.Int)
+	for range times {
+		multiplier.Mul(multiplier, shift)
+		multiplier.Add(multiplier, one)
+	}
+	return multiplier
+}
+
+// basePow returns the selected base raised to the power n
+func basePow(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(*base)), big.NewInt(int64(n)), nil)
+}
+
+func numDigits(n *big.Int) int {
+	return len(formatID(bigMax(n, one)))
+}
+
+func bigMin(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) > 0 {
+		return a
+	}
+	return b
+}
 
+func checkRepeatedInvalid(id string) bool {
 	// Try every possible block length from 1 up to n/2. We only need to check up to n/2 as there can't be repeats longer than half the string length.
-	for blockLen := 1; blockLen <= idLength/2; blockLen++ {
+	for blockLen := 1; blockLen <= len(id)/2; blockLen++ {
 		// The string length must be a multiple of blockLen or there is no duplicate pattern.
-		if idLength%blockLen != 0 {
+		if len(id)%blockLen != 0 {
 			continue
 		}
+		if checkRepeatedBlock(id, blockLen) {
+			return true
+		}
+	}
+
+	return false
+}
 
-		pattern := id[0:blockLen]
-		allMatch := true
+// checkRepeatedBlock reports whether id is its first blockLen characters repeated, id must be a multiple of blockLen long
+func checkRepeatedBlock(id string, blockLen int) bool {
+	if blockLen == 0 || blockLen == len(id) {
+		return false
+	}
+	pattern := id[0:blockLen]
 
-		// Check that every subsequent block matches the first one.
-		// e.g. if pattern is "12" and blockLen is 2, check id[2:4], id[4:6], etc that they are all "12"
-		for i := blockLen; i < idLength; i += blockLen {
-			if id[i:i+blockLen] != pattern {
-				allMatch = false
-				break
+	// Check that every subsequent block matches the first one.
+	// e.g. if pattern is "12" and blockLen is 2, check id[2:4], id[4:6], etc that they are all "12"
+	for i := blockLen; i < len(id); i += blockLen {
+		if id[i:i+blockLen] != pattern {
+			return false
+		}
+	}
+	return true
+}
+
+// writeReport writes the count and sum of invalid ids under each rule for every range in input order.
+// Ranges are reported as given, so ids shared by overlapping ranges appear under each of them.
+func writeReport(format string, includeIDs bool, rules []Rule) error {
+	calcInvalid(ranges, rules)
+	reports := []rangeReport{}
+	for _, r := range ranges {
+		row := rangeReport{Start: r.start, End: r.end}
+		for idx, rule := range rules {
+			ruleRow := ruleReport{Rule: rule.Name(), Count: r.totals[idx].count, Sum: r.totals[idx].sum}
+			if includeIDs {
+				ruleRow.IDs = r.invalidIDs(rule)
 			}
+			row.Rules = append(row.Rules, ruleRow)
 		}
+		reports = append(reports, row)
+	}
 
-		if allMatch {
-			return true
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case "csv":
+		return writeCSVReport(reports, includeIDs, rules)
+	}
+	return fmt.Errorf("unknown report format %q, expected csv or json", format)
+}
+
+// writeCSVReport writes one row per range with a count and sum column (and ids column if requested) per rule
+// for example with the twice rule:
+// start,end,exactly-twice count,exactly-twice sum
+// 11,22,2,33
+func writeCSVReport(reports []rangeReport, includeIDs bool, rules []Rule) error {
+	writer := csv.NewWriter(os.Stdout)
+	header := []string{"start", "end"}
+	for _, rule := range rules {
+		header = append(header, rule.Name()+" count", rule.Name()+" sum")
+		if includeIDs {
+			header = append(header, rule.Name()+" ids")
 		}
 	}
+	writer.Write(header)
 
-	return false
+	for _, row := range reports {
+		record := []string{row.Start.String(), row.End.String()}
+		for _, ruleRow := range row.Rules {
+			record = append(record, ruleRow.Count.String(), ruleRow.Sum.String())
+			if includeIDs {
+				ids := []string{}
+				for _, id := range ruleRow.IDs {
+					ids = append(ids, id.String())
+				}
+				record = append(record, strings.Join(ids, " "))
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
 }
 
 func main() {
-	partOne, partTwo := calcInvalid()
-	fmt.Printf("PartOne invalid sum: %d\n", partOne)
-	fmt.Printf("PartTwo invalid sum: %d\n", partTwo)
+	if *report != "" {
+		if err := writeReport(*report, *reportIDs, rules); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	sums := calcInvalid(mergeRanges(ranges), rules)
+	for idx, rule := range rules {
+		label := rule.Name()
+		if partLabels != nil {
+			label = partLabels[idx]
+		}
+		fmt.Printf("%s invalid sum: %s\n", label, sums[idx])
+	}
+	if *overlaps {
+		reportOverlaps(rules, sums)
+	}
 }
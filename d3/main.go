@@ -2,9 +2,11 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"math/big"
 	"os"
-	"strconv"
+	"strings"
 )
 
 type bank struct {
@@ -12,88 +14,359 @@ type bank struct {
 	raw       []rune
 }
 
-var banks []bank
+// selection is the batteries chosen from a bank, indices are positions in the bank in order
+type selection struct {
+	indices []int
+	voltage string
+}
+
+// constraints limit which batteries can be switched on together to match the hardware wiring
+type constraints struct {
+	noAdjacent bool
+	// segmentSize splits the bank into fixed size segments of which at most perSegment batteries can be chosen, 0 disables it
+	segmentSize int
+	perSegment  int
+	minimise    bool
+}
+
+// dpEntry is the best digits that can be chosen from a dynamic programming state, ok is false when nothing fits
+type dpEntry struct {
+	ok     bool
+	digits string
+}
 
-func init() {
+const (
+	highlightStart = "\033[1;32m"
+	highlightEnd   = "\033[0m"
+)
+
+var (
+	banks []bank
+
+	allLengths    = flag.Bool("all-lengths", false, "report every bank's best voltage for each selection length from 1 to the bank length")
+	partOneLength = flag.Int("part-one", 2, "number of batteries to switch on in each bank for part one")
+	partTwoLength = flag.Int("part-two", 12, "number of batteries to switch on in each bank for part two")
+	showLength    = flag.Int("show", 0, "print each bank with the batteries chosen for this selection length highlighted")
+	showBrackets  = flag.Bool("brackets", false, "mark chosen batteries with [] instead of colour when using -show")
+	noAdjacent    = flag.Bool("no-adjacent", false, "never choose two neighbouring batteries")
+	segmentSize   = flag.Int("segment", 0, "split each bank into segments of this many batteries, 0 for no segments")
+	perSegment    = flag.Int("per-segment", 1, "most batteries that can be chosen from each segment when using -segment")
+	minimise      = flag.Bool("minimise", false, "choose the smallest voltage instead of the largest")
+
+	selectionRules constraints
+)
+
+// readBanks parses the flags and reads the banks from stdin, it runs from main rather than init
+// so the tests can use the package without a worksheet on stdin
+func readBanks() {
+	flag.Parse()
+	if *partOneLength < 1 || *partTwoLength < 1 {
+		fmt.Fprintf(os.Stderr, "part-one and part-two must choose at least 1 battery, got %d and %d\n", *partOneLength, *partTwoLength)
+		os.Exit(1)
+	}
+	if *segmentSize < 0 || *perSegment < 1 {
+		fmt.Fprintf(os.Stderr, "segment must be 0 or more and per-segment at least 1, got %d and %d\n", *segmentSize, *perSegment)
+		os.Exit(1)
+	}
+	selectionRules = constraints{
+		noAdjacent:  *noAdjacent,
+		segmentSize: *segmentSize,
+		perSegment:  *perSegment,
+		minimise:    *minimise,
+	}
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
-		line := scanner.Text()
-		raw := []rune(line)
-		batteries := make([]int, len(raw))
+		// Windows line endings leave a \r on the end of each line
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		b, err := parseBank(line, len(banks)+1)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		banks = append(banks, b)
+	}
+}
+
+// parseBank converts a line of digits into a bank, anything other than 0-9 is rejected
+// as int(ch - '0') would quietly turn it into a negative or out of range battery
+func parseBank(line string, bankNumber int) (bank, error) {
+	raw := []rune(line)
+	batteries := make([]int, len(raw))
 
-		for i, ch := range raw {
-			batteries[i] = int(ch - '0')
+	for i, ch := range raw {
+		if ch < '0' || ch > '9' {
+			return bank{}, fmt.Errorf("bank %d column %d: %q is not a battery digit", bankNumber, i+1, ch)
 		}
+		batteries[i] = int(ch - '0')
+	}
+
+	return bank{
+		raw:       raw,
+		batteries: batteries,
+	}, nil
+}
+
+// selectBatteries returns the indices of the count batteries that make the largest voltage, in bank order.
+// It is a single pass greedy using a stack: a battery pops any smaller battery before it off the stack
+// as putting the bigger digit earlier always gives a bigger number, but only while we can still afford to
+// drop batteries (len - count of them) and still have count left.
+// for example, picking 2 from [8,1,9,2]:
+// 8 -> [8], 1 -> [8,1], 9 pops 1 and 8 (2 drops used) -> [9], 2 -> [9,2]
+func (b bank) selectBatteries(count int) []int {
+	drops := len(b.batteries) - count
+	stack := make([]int, 0, len(b.batteries))
+	for i, battery := range b.batteries {
+		for drops > 0 && len(stack) > 0 && b.batteries[stack[len(stack)-1]] < battery {
+			stack = stack[:len(stack)-1]
+			drops--
+		}
+		stack = append(stack, i)
+	}
+	// any drops left over come off the end as those are the smallest remaining positions
+	return stack[:count]
+}
+
+func (c constraints) unconstrained() bool {
+	return !c.noAdjacent && c.segmentSize == 0 && !c.minimise
+}
 
-		banks = append(banks, bank{
-			raw:       raw,
-			batteries: batteries,
-		})
+// better reports whether voltage a beats b, both always have the same number of digits so comparing the strings is enough
+func (c constraints) better(a, b string) bool {
+	if c.minimise {
+		return a < b
 	}
+	return a > b
 }
 
-func maxIndex(candidates []int, remainingLength int) int {
-	currentMax := -1
-	idx := 0
-	for i, candidate := range candidates {
-		// ensure enough elements remain after choosing this candidate
-		// as we need to pick remainingLength elements in total
-		// for example, if we have
-		// [0,1,2,3,4] and need to pick 3 elements
-		// at index 2 (value 2) we can only pick two more elements (3,4)
-		// so we cannot pick index 2 as 2 + 3 > 5 (len of candidates)
-		// therefore in that scenario we can only pick between indices 0 and 1
-		// which results in idx = 1 being returned
-		if i+remainingLength <= len(candidates) && candidate > currentMax {
-			currentMax = candidate
-			idx = i
+// allows reports whether the chosen indices (in bank order) break none of the wiring constraints
+func (c constraints) allows(indices []int) bool {
+	perSegmentCount := map[int]int{}
+	for i, idx := range indices {
+		if c.noAdjacent && i > 0 && indices[i-1]+1 == idx {
+			return false
+		}
+		if c.segmentSize > 0 {
+			perSegmentCount[idx/c.segmentSize]++
+			if perSegmentCount[idx/c.segmentSize] > c.perSegment {
+				return false
+			}
 		}
 	}
-	return idx
+	return true
 }
 
-func aToIIgnoreError(s string) int {
-	result, _ := strconv.Atoi(s)
-	return result
+// constrainedSelect picks count batteries under the wiring constraints using dynamic programming.
+// The state is (position, batteries still to pick, batteries already picked from the current segment)
+// and each state holds the best digits that can still be picked from there, built from the end of the bank backwards.
+// At each position we either skip the battery or take it, taking moves on two places when neighbours are
+// not allowed and uses up one of the segment's slots, the count resetting whenever we cross into a new segment.
+// Every candidate for a state has the same number of digits so the best is simply the largest (or smallest) string.
+func (b bank) constrainedSelect(count int, c constraints) ([]int, bool) {
+	n := len(b.batteries)
+	usedStates := 1
+	if c.segmentSize > 0 {
+		usedStates = c.perSegment + 1
+	}
+	segment := func(pos int) int {
+		if c.segmentSize == 0 {
+			return 0
+		}
+		return pos / c.segmentSize
+	}
+	// carry the picked count from one position to the next, a new segment starts with nothing picked
+	carry := func(from, to, used int) int {
+		if c.segmentSize == 0 || segment(from) != segment(to) {
+			return 0
+		}
+		return used
+	}
+	takeNext := func(pos int) int {
+		if c.noAdjacent {
+			return pos + 2
+		}
+		return pos + 1
+	}
+
+	// n+2 positions as taking the last battery with noAdjacent jumps past the end
+	table := make([][][]dpEntry, n+2)
+	for i := range table {
+		table[i] = make([][]dpEntry, count+1)
+		for r := range table[i] {
+			table[i][r] = make([]dpEntry, usedStates)
+		}
+		for used := range usedStates {
+			table[i][0][used] = dpEntry{ok: true}
+		}
+	}
+
+	options := func(i, remaining, used int) (dpEntry, dpEntry) {
+		skip := table[i+1][remaining][carry(i, i+1, used)]
+		take := dpEntry{}
+		if c.segmentSize == 0 || used < c.perSegment {
+			next := takeNext(i)
+			rest := table[next][remaining-1][carry(i, next, used+1)]
+			take = dpEntry{ok: rest.ok, digits: string(b.raw[i]) + rest.digits}
+		}
+		return skip, take
+	}
+	// prefer taking on a tie so the earliest battery is used, matching the unconstrained greedy
+	takeWins := func(skip, take dpEntry) bool {
+		return take.ok && (!skip.ok || !c.better(skip.digits, take.digits))
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for remaining := 1; remaining <= count; remaining++ {
+			for used := range usedStates {
+				skip, take := options(i, remaining, used)
+				if takeWins(skip, take) {
+					table[i][remaining][used] = take
+				} else {
+					table[i][remaining][used] = skip
+				}
+			}
+		}
+	}
+	if !table[0][count][0].ok {
+		return nil, false
+	}
+
+	// walk the table forwards repeating the same decisions to recover the positions
+	indices := []int{}
+	pos, used := 0, 0
+	for remaining := count; remaining > 0; {
+		skip, take := options(pos, remaining, used)
+		if takeWins(skip, take) {
+			indices = append(indices, pos)
+			next := takeNext(pos)
+			used = carry(pos, next, used+1)
+			pos = next
+			remaining--
+		} else {
+			used = carry(pos, pos+1, used)
+			pos++
+		}
+	}
+	return indices, true
 }
 
-func (b bank) maxVoltage(expectedLength int) int {
+// maxVoltage returns the chosen batteries and their voltage as a decimal string,
+// anything over 18 batteries no longer fits in an int.
+// ok is false when no choice of expectedLength batteries satisfies the constraints.
+func (b bank) maxVoltage(expectedLength int) (selection, bool) {
+	if expectedLength > len(b.batteries) {
+		return selection{}, false
+	}
+	var indices []int
+	if selectionRules.unconstrained() {
+		indices = b.selectBatteries(expectedLength)
+	} else {
+		var ok bool
+		indices, ok = b.constrainedSelect(expectedLength, selectionRules)
+		if !ok {
+			return selection{}, false
+		}
+	}
 	results := []rune{}
-	offset := 0
-	for i := expectedLength; i > 0; i-- {
-		maxIdx := maxIndex(b.batteries[offset:], i)
-		results = append(results, b.raw[maxIdx+offset])
-		// since maxId is relative to the slice when maxId returns 0 its actually b.batteries[offset + maxId]
-		// +1 just progresses past the found max for the next search as the slice indexing is inclusive of the start index
-		offset = offset + maxIdx + 1
+	for _, idx := range indices {
+		results = append(results, b.raw[idx])
 	}
-	return aToIIgnoreError(string(results))
+	return selection{indices: indices, voltage: string(results)}, true
 }
 
-func partOne() int {
-	result := 0
-	for _, b := range banks {
-		result += b.maxVoltage(2)
+// highlight returns the bank's raw digits with the chosen batteries marked, either in colour or wrapped in brackets
+// e.g. 811111111111119 choosing 2 gives [8]1111111111111[9]
+func (b bank) highlight(chosen []int, brackets bool) string {
+	var sb strings.Builder
+	next := 0
+	for i, ch := range b.raw {
+		if next < len(chosen) && chosen[next] == i {
+			next++
+			if brackets {
+				sb.WriteString("[" + string(ch) + "]")
+			} else {
+				sb.WriteString(highlightStart + string(ch) + highlightEnd)
+			}
+			continue
+		}
+		sb.WriteRune(ch)
 	}
+	return sb.String()
+}
 
-	return result
+// showSelections prints every bank with the batteries to switch on highlighted, followed by the chosen positions
+func showSelections(length int, brackets bool) {
+	for i, b := range banks {
+		if len(b.batteries) < length {
+			fmt.Printf("bank %d: %s only has %d batteries, fewer than %d\n", i+1, string(b.raw), len(b.batteries), length)
+			continue
+		}
+		chosen, ok := b.maxVoltage(length)
+		if !ok {
+			fmt.Printf("bank %d: %s no selection of %d batteries possible\n", i+1, string(b.raw), length)
+			continue
+		}
+		fmt.Printf("bank %d: %s voltage %s positions %v\n", i+1, b.highlight(chosen.indices, brackets), chosen.voltage, chosen.indices)
+	}
 }
 
-func partTwo() int {
-	result := 0
-	for _, b := range banks {
-		result += b.maxVoltage(12)
+// sumVoltages adds up the best voltage of every bank picking length batteries from each
+func sumVoltages(length int) *big.Int {
+	result := new(big.Int)
+	for i, b := range banks {
+		if len(b.batteries) < length {
+			fmt.Fprintf(os.Stderr, "bank %d: only has %d batteries, fewer than %d, skipping\n", i+1, len(b.batteries), length)
+			continue
+		}
+		chosen, ok := b.maxVoltage(length)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "bank %d: no selection of %d batteries possible, skipping\n", i+1, length)
+			continue
+		}
+		voltage, _ := new(big.Int).SetString(chosen.voltage, 10)
+		result.Add(result, voltage)
+	}
+	return result
+}
 
+// reportAllLengths prints the best voltage of every bank for each selection length from 1 to the bank length
+func reportAllLengths() {
+	for i, b := range banks {
+		for length := 1; length <= len(b.batteries); length++ {
+			chosen, ok := b.maxVoltage(length)
+			if !ok {
+				// constraints only get harder to meet with more batteries
+				break
+			}
+			fmt.Printf("bank %d length %d: %s\n", i+1, length, chosen.voltage)
+		}
 	}
+}
 
-	return result
+func partOne() *big.Int {
+	return sumVoltages(*partOneLength)
+}
+
+func partTwo() *big.Int {
+	return sumVoltages(*partTwoLength)
 }
 
 func main() {
+	readBanks()
+	if *allLengths {
+		reportAllLengths()
+		return
+	}
+	if *showLength > 0 {
+		showSelections(*showLength, *showBrackets)
+		return
+	}
 
 	partOne := partOne()
 	partTwo := partTwo()
-	fmt.Printf("PartOne invalid sum: %d\n", partOne)
-	fmt.Printf("PartTwo invalid sum: %d\n", partTwo)
+	fmt.Printf("PartOne invalid sum: %s\n", partOne)
+	fmt.Printf("PartTwo invalid sum: %s\n", partTwo)
 
 }
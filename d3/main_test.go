@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+	"testing"
+)
+
+// bruteForceSelect tries every combination of count batteries, only usable on small banks
+func (b bank) bruteForceSelect(count int, c constraints) (string, bool) {
+	best := ""
+	found := false
+	for mask := 0; mask < 1<<len(b.batteries); mask++ {
+		if bits.OnesCount(uint(mask)) != count {
+			continue
+		}
+		indices := []int{}
+		for i := range b.batteries {
+			if mask&(1<<i) != 0 {
+				indices = append(indices, i)
+			}
+		}
+		if !c.allows(indices) {
+			continue
+		}
+		digits := ""
+		for _, idx := range indices {
+			digits += string(b.raw[idx])
+		}
+		if !found || c.better(digits, best) {
+			best = digits
+			found = true
+		}
+	}
+	return best, found
+}
+
+// TestConstrainedSelectMatchesBruteForce compares the dynamic programming selection with brute force for every
+// selection length of randomly generated small banks. Digits are limited to 1-4 so plenty of ties are exercised.
+func TestConstrainedSelectMatchesBruteForce(t *testing.T) {
+	rules := []constraints{
+		{},
+		{minimise: true},
+		{noAdjacent: true},
+		{segmentSize: 3, perSegment: 1},
+		{segmentSize: 4, perSegment: 2},
+		{segmentSize: 4, perSegment: 2, noAdjacent: true},
+		{segmentSize: 5, perSegment: 3, noAdjacent: true, minimise: true},
+	}
+	for _, c := range rules {
+		t.Run(fmt.Sprintf("%+v", c), func(t *testing.T) {
+			random := rand.New(rand.NewPCG(3, 2025))
+			for range 300 {
+				raw := make([]rune, 1+random.IntN(12))
+				batteries := make([]int, len(raw))
+				for i := range raw {
+					batteries[i] = 1 + random.IntN(4)
+					raw[i] = rune('0' + batteries[i])
+				}
+				b := bank{raw: raw, batteries: batteries}
+				for count := 1; count <= len(raw); count++ {
+					expected, expectedOk := b.bruteForceSelect(count, c)
+					indices, ok := b.constrainedSelect(count, c)
+					got := ""
+					for _, idx := range indices {
+						got += string(raw[idx])
+					}
+					if ok != expectedOk || got != expected {
+						t.Fatalf("%s picking %d: got %q (%v) expected %q (%v)", string(raw), count, got, ok, expected, expectedOk)
+					}
+					if ok && !c.allows(indices) {
+						t.Fatalf("%s picking %d: chose %v which breaks the constraints", string(raw), count, indices)
+					}
+				}
+			}
+		})
+	}
+}
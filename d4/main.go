@@ -2,10 +2,39 @@ package main
 
 import (
 	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math/bits"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-var grid [][]cell
+var (
+	grid [][]cell
+	// compactGrid replaces grid when running with -compact
+	compactGrid *bitGrid
+
+	heatmap   = flag.Bool("heatmap", false, "print an ASCII heatmap of the wave each roll was removed in")
+	pngPath   = flag.String("png", "", "write a PNG heatmap of the removal waves to this file")
+	gifPath   = flag.String("gif", "", "write an animated GIF of the grid after each wave to this file")
+	cellScale = flag.Int("scale", 4, "pixels per grid cell in the PNG and GIF")
+	gifDelay  = flag.Int("delay", 20, "delay between GIF frames in hundredths of a second")
+
+	neighbourhood   = flag.String("neighbourhood", "moore", "cells counted as adjacent: moore, von-neumann or custom")
+	radius          = flag.Int("radius", 1, "how far the moore or von-neumann neighbourhood reaches")
+	customOffsets   = flag.String("offsets", "", "row,col offsets for the custom neighbourhood separated by ;, e.g. -1,0;1,0")
+	accessThreshold = flag.Int("threshold", 4, "a roll is accessible when fewer than this many adjacent cells hold rolls")
+	torus           = flag.Bool("torus", false, "wrap the grid edges around so every cell has a full neighbourhood")
+	compact         = flag.Bool("compact", false, "store the grid one bit per cell for very large maps, only the part counts are output")
+	remaining       = flag.Bool("remaining", false, "list the rolls that can never be removed grouped into connected clusters")
+)
 
 type cell struct {
 	row           int
@@ -13,6 +42,30 @@ type cell struct {
 	val           rune
 	adjacentRolls int
 	accessible    bool
+	// removedWave is the wave (starting at 1) the roll was removed in, 0 if it never was
+	removedWave int
+}
+
+// bitGrid stores one bit per cell, set for a roll of paper, packed 64 cells to a word.
+// Rows keep their own length so ragged input works, anything outside a row reads as empty floor.
+type bitGrid struct {
+	rows [][]uint64
+}
+
+// wordRemoval is a set of rolls in one word of a row to clear at the end of a wave
+type wordRemoval struct {
+	row  int
+	word int
+	mask uint64
+}
+
+// cluster is a connected group of rolls left once removal has stopped
+type cluster struct {
+	rolls  []cell
+	minRow int
+	maxRow int
+	minCol int
+	maxCol int
 }
 
 type directionMap struct {
@@ -21,7 +74,8 @@ type directionMap struct {
 	label     string
 }
 
-var directions = [...]directionMap{
+// directions defaults to the eight cells around a roll, init rebuilds it from the neighbourhood flags
+var directions = []directionMap{
 	{0, 1, "right"},
 	{1, 0, "down"},
 	{0, -1, "left"},
@@ -36,8 +90,47 @@ const (
 	RollOfPaper = '@'
 )
 
+// waveSymbols are used by the ASCII heatmap for waves 1 onwards, later waves share the final symbol
+const waveSymbols = "123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ+"
+
+var (
+	emptyColour     = color.RGBA{255, 255, 255, 255}
+	remainingColour = color.RGBA{40, 40, 40, 255}
+	firstWaveColour = color.RGBA{255, 220, 0, 255}
+	lastWaveColour  = color.RGBA{200, 0, 60, 255}
+)
+
 func init() {
+	flag.Parse()
+	var err error
+	directions, err = buildDirections(*neighbourhood, *radius, *customOffsets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *accessThreshold < 0 {
+		fmt.Fprintf(os.Stderr, "threshold must be 0 or more, got %d\n", *accessThreshold)
+		os.Exit(1)
+	}
+	if *cellScale < 1 || *gifDelay < 0 {
+		fmt.Fprintf(os.Stderr, "scale must be at least 1 and delay 0 or more, got %d and %d\n", *cellScale, *gifDelay)
+		os.Exit(1)
+	}
+	if *compact && (*torus || *heatmap || *pngPath != "" || *gifPath != "" || *remaining) {
+		fmt.Fprintln(os.Stderr, "-compact can't be combined with -torus, -heatmap, -png, -gif or -remaining")
+		os.Exit(1)
+	}
+
 	scanner := bufio.NewScanner(os.Stdin)
+	// very wide warehouse maps have lines well past the default 64KB limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	if *compact {
+		compactGrid = &bitGrid{}
+		for scanner.Scan() {
+			compactGrid.addRow(scanner.Text())
+		}
+		return
+	}
 	rowCount := 0
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -56,12 +149,125 @@ func init() {
 		rowCount++
 		grid = append(grid, row)
 	}
+	// wrapping a column needs one width for every row, otherwise a cell's neighbour
+	// wouldn't count it back and removing it would decrement the wrong cells
+	if *torus {
+		for i, row := range grid {
+			if len(row) != len(grid[0]) {
+				fmt.Fprintf(os.Stderr, "-torus needs every row to be the same width, row %d is %d wide but row 1 is %d\n", i+1, len(row), len(grid[0]))
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+// buildDirections returns the offsets counted as adjacent for a neighbourhood.
+// moore is every cell within radius in both directions (the 8 surrounding cells for radius 1),
+// von-neumann only those within radius steps up/down/left/right (the 4 touching sides for radius 1)
+// and custom takes the offsets as given, e.g. "-1,0;1,0" for only the cells above and below.
+func buildDirections(kind string, radius int, offsets string) ([]directionMap, error) {
+	result := []directionMap{}
+	switch kind {
+	case "moore", "von-neumann":
+		if radius < 1 {
+			return nil, fmt.Errorf("radius must be at least 1, got %d", radius)
+		}
+		for rowOffset := -radius; rowOffset <= radius; rowOffset++ {
+			for colOffset := -radius; colOffset <= radius; colOffset++ {
+				if rowOffset == 0 && colOffset == 0 {
+					continue
+				}
+				if kind == "von-neumann" && abs(rowOffset)+abs(colOffset) > radius {
+					continue
+				}
+				result = append(result, directionMap{rowOffset, colOffset, directionLabel(rowOffset, colOffset)})
+			}
+		}
+	case "custom":
+		for _, pair := range strings.Split(offsets, ";") {
+			parts := strings.Split(strings.TrimSpace(pair), ",")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("offset %q should be row,col", pair)
+			}
+			rowOffset, rowErr := strconv.Atoi(strings.TrimSpace(parts[0]))
+			colOffset, colErr := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if rowErr != nil || colErr != nil {
+				return nil, fmt.Errorf("offset %q should be two whole numbers", pair)
+			}
+			if rowOffset == 0 && colOffset == 0 {
+				return nil, fmt.Errorf("offset 0,0 is the roll itself")
+			}
+			result = append(result, directionMap{rowOffset, colOffset, directionLabel(rowOffset, colOffset)})
+		}
+	default:
+		return nil, fmt.Errorf("unknown neighbourhood %q, expected moore, von-neumann or custom", kind)
+	}
+	return result, nil
+}
+
+// directionLabel names an offset in the same style as the original table, e.g. upLeft or down2Right
+func directionLabel(rowOffset, colOffset int) string {
+	step := func(name string, n int) string {
+		if abs(n) == 1 {
+			return name
+		}
+		return name + strconv.Itoa(abs(n))
+	}
+	vertical := ""
+	if rowOffset < 0 {
+		vertical = step("up", rowOffset)
+	} else if rowOffset > 0 {
+		vertical = step("down", rowOffset)
+	}
+	horizontal := ""
+	if colOffset < 0 {
+		horizontal = step("left", colOffset)
+	} else if colOffset > 0 {
+		horizontal = step("right", colOffset)
+	}
+	if vertical != "" && horizontal != "" {
+		return vertical + strings.ToUpper(horizontal[:1]) + horizontal[1:]
+	}
+	return vertical + horizontal
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
 
 func withinGrindBoundary(row, col int, g [][]cell) bool {
 	return row > -1 && col > -1 && row < len(g) && col < len(g[row])
 }
 
+// neighbourOf applies the direction to row, col (backwards when reverse is set) and reports whether the result
+// is a cell on the grid. On a torus the edges wrap around so it always is, unless it wraps back onto the cell itself.
+func neighbourOf(row, col int, direction directionMap, reverse bool, g [][]cell) (int, int, bool) {
+	rowOffset, colOffset := direction.rowOffset, direction.colOffset
+	if reverse {
+		rowOffset, colOffset = -rowOffset, -colOffset
+	}
+	offsetRow := row + rowOffset
+	offsetColumn := col + colOffset
+	if !*torus {
+		return offsetRow, offsetColumn, withinGrindBoundary(offsetRow, offsetColumn, g)
+	}
+
+	offsetRow = wrap(offsetRow, len(g))
+	if len(g[offsetRow]) == 0 {
+		return 0, 0, false
+	}
+	offsetColumn = wrap(offsetColumn, len(g[offsetRow]))
+	return offsetRow, offsetColumn, offsetRow != row || offsetColumn != col
+}
+
+// wrap is n mod size but always positive, Go's % keeps the sign of n
+func wrap(n, size int) int {
+	return ((n % size) + size) % size
+}
+
 func deepCopyGrid(original [][]cell) [][]cell {
 	copied := make([][]cell, len(original))
 	for i := range original {
@@ -74,9 +280,8 @@ func deepCopyGrid(original [][]cell) [][]cell {
 func calcAdjacent(c cell, g [][]cell) int {
 	adjacent := 0
 	for _, direction := range directions {
-		offsetRow := c.row + direction.rowOffset
-		offsetColumn := c.col + direction.colOffset
-		if !withinGrindBoundary(offsetRow, offsetColumn, g) {
+		offsetRow, offsetColumn, ok := neighbourOf(c.row, c.col, direction, false, g)
+		if !ok {
 			continue
 		}
 		if g[offsetRow][offsetColumn].val == RollOfPaper {
@@ -92,49 +297,424 @@ func partOne() int {
 		for _, c := range r {
 			cell := grid[c.row][c.col]
 			adjacent := calcAdjacent(cell, grid)
-			if cell.val == RollOfPaper && adjacent < 4 {
+			if cell.val == RollOfPaper && adjacent < *accessThreshold {
 				accessibleCount++
 			}
-			// count accessible rolls of paper, i.e. those with less than 4 adjacent rolls and the cell itself a roll of papers
+			// count accessible rolls of paper, i.e. those with less than threshold (4 by default) adjacent rolls and the cell itself a roll of papers
 
 		}
 	}
 	return accessibleCount
 }
 
-func removalCandidates(g [][]cell) []cell {
-	result := []cell{}
-	for _, r := range g {
+// removeRolls removes rolls with a worklist instead of rescanning the whole grid every wave.
+// Each roll's adjacent count is worked out once, then removing a roll only decrements its neighbours,
+// and any neighbour that drops below the threshold is queued for the next wave. accessible marks rolls that are already
+// queued so they are never queued twice, meaning every roll is touched a constant number of times.
+// Removing wave by wave still gives the same waves as rescanning: a roll joins the next wave as soon as
+// the rolls removed so far leave it with fewer than threshold neighbours.
+// It returns the final grid with removedWave recorded on every removed roll, the removed count and the number of waves.
+func removeRolls() ([][]cell, int, int) {
+	currentGrid := deepCopyGrid(grid)
+	wave := []cell{}
+	for _, r := range currentGrid {
 		for _, c := range r {
-			cell := g[c.row][c.col]
-			adjacent := calcAdjacent(cell, g)
-			if cell.val == RollOfPaper && adjacent < 4 {
-				result = append(result, cell)
+			if c.val != RollOfPaper {
+				continue
+			}
+			current := &currentGrid[c.row][c.col]
+			current.adjacentRolls = calcAdjacent(c, currentGrid)
+			if current.adjacentRolls < *accessThreshold {
+				current.accessible = true
+				wave = append(wave, *current)
 			}
 		}
 	}
-	return result
+
+	removed := 0
+	waveNumber := 0
+	for len(wave) > 0 {
+		waveNumber++
+		removed += len(wave)
+		// mark the whole wave first so rolls in the same wave don't decrement each other
+		for _, c := range wave {
+			currentGrid[c.row][c.col].val = 'x'
+			currentGrid[c.row][c.col].removedWave = waveNumber
+		}
+		next := []cell{}
+		for _, c := range wave {
+			for _, direction := range directions {
+				// walk the direction backwards to find the rolls that count this one as adjacent,
+				// which matters for custom offsets that aren't symmetric
+				offsetRow, offsetColumn, ok := neighbourOf(c.row, c.col, direction, true, currentGrid)
+				if !ok {
+					continue
+				}
+				neighbour := &currentGrid[offsetRow][offsetColumn]
+				if neighbour.val != RollOfPaper {
+					continue
+				}
+				neighbour.adjacentRolls--
+				if !neighbour.accessible && neighbour.adjacentRolls < *accessThreshold {
+					neighbour.accessible = true
+					next = append(next, *neighbour)
+				}
+			}
+		}
+		wave = next
+	}
+	return currentGrid, removed, waveNumber
 }
 
-// Bit wasteful as it doesn't reuse the result from part one but it is what it is for now
 func partTwo() int {
-	currentGrid := deepCopyGrid(grid)
-	candidates := removalCandidates(currentGrid)
+	_, removed, _ := removeRolls()
+	return removed
+}
+
+// printHeatmap prints the grid with each removed roll replaced by the wave it went in,
+// rolls that were never removed stay as @
+func printHeatmap(g [][]cell) {
+	for _, r := range g {
+		var sb strings.Builder
+		for _, c := range r {
+			if c.removedWave > 0 {
+				sb.WriteByte(waveSymbols[min(c.removedWave, len(waveSymbols))-1])
+			} else {
+				sb.WriteRune(c.val)
+			}
+		}
+		fmt.Println(sb.String())
+	}
+}
+
+// waveColour shades removed rolls from yellow for the first wave through to red for the last
+func waveColour(wave, waves int) color.RGBA {
+	if waves <= 1 {
+		return firstWaveColour
+	}
+	t := float64(wave-1) / float64(waves-1)
+	blend := func(a, b uint8) uint8 {
+		return uint8(float64(a) + t*(float64(b)-float64(a)))
+	}
+	return color.RGBA{
+		blend(firstWaveColour.R, lastWaveColour.R),
+		blend(firstWaveColour.G, lastWaveColour.G),
+		blend(firstWaveColour.B, lastWaveColour.B),
+		255,
+	}
+}
+
+func gridWidth(g [][]cell) int {
+	width := 0
+	for _, r := range g {
+		width = max(width, len(r))
+	}
+	return width
+}
+
+// fillCell colours the scale x scale block of pixels for a grid cell
+func fillCell(img draw.Image, row, col, scale int, c color.Color) {
+	for y := row * scale; y < (row+1)*scale; y++ {
+		for x := col * scale; x < (col+1)*scale; x++ {
+			img.Set(x, y, c)
+		}
+	}
+}
+
+// writePNG writes the heatmap as an image, removed rolls coloured by wave and remaining rolls dark grey
+func writePNG(path string, g [][]cell, waves, scale int) error {
+	img := image.NewRGBA(image.Rect(0, 0, gridWidth(g)*scale, len(g)*scale))
+	for _, r := range g {
+		for _, c := range r {
+			colour := emptyColour
+			if c.removedWave > 0 {
+				colour = waveColour(c.removedWave, waves)
+			} else if c.val == RollOfPaper {
+				colour = remainingColour
+			}
+			fillCell(img, c.row, c.col, scale, colour)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
+
+// writeGIF writes one frame for the starting grid and one after each wave.
+// Rolls removed by the wave just shown are highlighted, earlier removals are shown as empty floor.
+func writeGIF(path string, g [][]cell, waves, scale, delay int) error {
+	palette := color.Palette{emptyColour, remainingColour, firstWaveColour}
+	const (
+		emptyIndex = iota
+		rollIndex
+		removedIndex
+	)
+
+	animation := &gif.GIF{}
+	bounds := image.Rect(0, 0, gridWidth(g)*scale, len(g)*scale)
+	for frame := 0; frame <= waves; frame++ {
+		img := image.NewPaletted(bounds, palette)
+		for _, r := range g {
+			for _, c := range r {
+				switch {
+				case c.removedWave > 0 && c.removedWave == frame:
+					fillCell(img, c.row, c.col, scale, palette[removedIndex])
+				case c.removedWave > frame || (c.removedWave == 0 && c.val == RollOfPaper):
+					fillCell(img, c.row, c.col, scale, palette[rollIndex])
+				}
+			}
+		}
+		animation.Image = append(animation.Image, img)
+		animation.Delay = append(animation.Delay, delay)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gif.EncodeAll(file, animation)
+}
+
+// addRow packs a line of the map, column c is bit c%64 of word c/64.
+// Rolls are ASCII so the line is read as bytes rather than runes.
+func (b *bitGrid) addRow(line string) {
+	row := make([]uint64, (len(line)+63)/64)
+	for col := 0; col < len(line); col++ {
+		if line[col] == RollOfPaper {
+			row[col/64] |= 1 << (col % 64)
+		}
+	}
+	b.rows = append(b.rows, row)
+}
+
+// cellsFrom returns the 64 cells of a row starting at column start as a word,
+// start can be negative or run off the end of the row as those cells read as empty.
+func (b *bitGrid) cellsFrom(row, start int) uint64 {
+	if row < 0 || row >= len(b.rows) {
+		return 0
+	}
+	words := b.rows[row]
+	get := func(i int) uint64 {
+		if i < 0 || i >= len(words) {
+			return 0
+		}
+		return words[i]
+	}
+	// floor division so negative starts pick the word to the left
+	word := start / 64
+	if start%64 < 0 {
+		word--
+	}
+	shift := start - word*64
+	if shift == 0 {
+		return get(word)
+	}
+	return get(word)>>shift | get(word+1)<<(64-shift)
+}
+
+// accessibleWord returns the rolls in one word of a row that have fewer than threshold adjacent rolls.
+// All 64 cells are counted at once: for each direction the neighbouring cells are lined up as a word
+// and added into bit-sliced counters, planes[p] holding bit p of every cell's count.
+// Adding a word is a ripple carry through the planes, e.g. a cell with count 3 (planes 0 and 1 set)
+// getting another neighbour clears planes 0 and 1 and sets plane 2, giving 4.
+// The counts are then compared with the threshold from the top plane down, the same way you would
+// compare two binary numbers by hand, keeping track of which cells are still equal so far.
+func (b *bitGrid) accessibleWord(row, word int, planes []uint64) uint64 {
+	rolls := b.rows[row][word]
+	if rolls == 0 {
+		return 0
+	}
+	clear(planes)
+	for _, direction := range directions {
+		carry := b.cellsFrom(row+direction.rowOffset, word*64+direction.colOffset)
+		for p := 0; p < len(planes) && carry != 0; p++ {
+			next := planes[p] & carry
+			planes[p] ^= carry
+			carry = next
+		}
+	}
+
+	less := uint64(0)
+	equal := ^uint64(0)
+	for p := len(planes) - 1; p >= 0; p-- {
+		if (*accessThreshold>>p)&1 == 1 {
+			less |= equal &^ planes[p]
+			equal &= planes[p]
+		} else {
+			equal &^= planes[p]
+		}
+	}
+	return rolls & less
+}
+
+// countPlanes is enough bit planes to hold both the largest possible count and the threshold
+func countPlanes() []uint64 {
+	return make([]uint64, bits.Len(uint(max(len(directions), *accessThreshold, 0))))
+}
+
+func (b *bitGrid) partOne() int {
+	planes := countPlanes()
+	accessibleCount := 0
+	for row := range b.rows {
+		for word := range b.rows[row] {
+			accessibleCount += bits.OnesCount64(b.accessibleWord(row, word, planes))
+		}
+	}
+	return accessibleCount
+}
+
+// partTwo removes rolls wave by wave, only rechecking rows close enough to a removal to have changed.
+// Each wave is worked out in full before clearing anything so it matches the cell by cell removal.
+func (b *bitGrid) partTwo() int {
+	reach := 0
+	for _, direction := range directions {
+		reach = max(reach, abs(direction.rowOffset))
+	}
+	planes := countPlanes()
+	active := make([]bool, len(b.rows))
+	for row := range active {
+		active[row] = true
+	}
+
 	removed := 0
+	for {
+		removals := []wordRemoval{}
+		for row := range b.rows {
+			if !active[row] {
+				continue
+			}
+			for word := range b.rows[row] {
+				if mask := b.accessibleWord(row, word, planes); mask != 0 {
+					removals = append(removals, wordRemoval{row, word, mask})
+				}
+			}
+		}
+		if len(removals) == 0 {
+			return removed
+		}
+
+		clear(active)
+		for _, r := range removals {
+			b.rows[r.row][r.word] &^= r.mask
+			removed += bits.OnesCount64(r.mask)
+			for row := max(r.row-reach, 0); row <= min(r.row+reach, len(b.rows)-1); row++ {
+				active[row] = true
+			}
+		}
+	}
+}
 
-	for len(candidates) > 0 {
-		removed += len(candidates)
-		for _, cell := range candidates {
-			currentGrid[cell.row][cell.col].val = 'x'
+// findClusters groups the rolls still standing into connected clusters, largest first.
+// Rolls are connected when one is in the other's neighbourhood, using the same directions (and wrapping)
+// as the removal so the clusters match the access rule being modelled.
+func findClusters(g [][]cell) []cluster {
+	visited := make([][]bool, len(g))
+	for i := range g {
+		visited[i] = make([]bool, len(g[i]))
+	}
 
+	clusters := []cluster{}
+	for _, r := range g {
+		for _, c := range r {
+			if c.val != RollOfPaper || visited[c.row][c.col] {
+				continue
+			}
+			visited[c.row][c.col] = true
+			current := cluster{minRow: c.row, maxRow: c.row, minCol: c.col, maxCol: c.col}
+			queue := []cell{c}
+			for len(queue) > 0 {
+				next := queue[0]
+				queue = queue[1:]
+				current.rolls = append(current.rolls, next)
+				current.minRow = min(current.minRow, next.row)
+				current.maxRow = max(current.maxRow, next.row)
+				current.minCol = min(current.minCol, next.col)
+				current.maxCol = max(current.maxCol, next.col)
+
+				for _, direction := range directions {
+					// both ways round so custom offsets that aren't symmetric still connect both rolls
+					for _, reverse := range []bool{false, true} {
+						offsetRow, offsetColumn, ok := neighbourOf(next.row, next.col, direction, reverse, g)
+						if !ok || visited[offsetRow][offsetColumn] || g[offsetRow][offsetColumn].val != RollOfPaper {
+							continue
+						}
+						visited[offsetRow][offsetColumn] = true
+						queue = append(queue, g[offsetRow][offsetColumn])
+					}
+				}
+			}
+			sort.Slice(current.rolls, func(i, j int) bool {
+				if current.rolls[i].row != current.rolls[j].row {
+					return current.rolls[i].row < current.rolls[j].row
+				}
+				return current.rolls[i].col < current.rolls[j].col
+			})
+			clusters = append(clusters, current)
 		}
-		currentGrid = deepCopyGrid(currentGrid)
-		candidates = removalCandidates(currentGrid)
 	}
-	return removed
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].rolls) > len(clusters[j].rolls)
+	})
+	return clusters
+}
+
+// printRemaining reports how many waves it took for the grid to stop changing and the clusters of rolls left behind
+func printRemaining(g [][]cell, waves int) {
+	clusters := findClusters(g)
+	total := 0
+	for _, c := range clusters {
+		total += len(c.rolls)
+	}
+	fmt.Printf("Stable after %d waves\n", waves)
+	fmt.Printf("%d rolls can never be removed, in %d clusters\n", total, len(clusters))
+	for i, c := range clusters {
+		positions := []string{}
+		for _, roll := range c.rolls {
+			positions = append(positions, fmt.Sprintf("(%d,%d)", roll.row, roll.col))
+		}
+		fmt.Printf("cluster %d: %d rolls, rows %d-%d, cols %d-%d: %s\n",
+			i+1, len(c.rolls), c.minRow, c.maxRow, c.minCol, c.maxCol, strings.Join(positions, " "))
+	}
 }
 
 func main() {
+	if *compact {
+		println("Part One:", compactGrid.partOne())
+		println("Part Two:", compactGrid.partTwo())
+		return
+	}
+
 	println("Part One:", partOne())
-	println("Part Two:", partTwo())
+	if !*heatmap && *pngPath == "" && *gifPath == "" && !*remaining {
+		println("Part Two:", partTwo())
+		return
+	}
+
+	// the outputs below need the removal's grid, so run it once here rather than through partTwo
+	removedGrid, removed, waves := removeRolls()
+	println("Part Two:", removed)
+	if *heatmap {
+		printHeatmap(removedGrid)
+	}
+	if *remaining {
+		printRemaining(removedGrid, waves)
+	}
+	if *pngPath != "" {
+		if err := writePNG(*pngPath, removedGrid, waves, *cellScale); err != nil {
+			fmt.Fprintln(os.Stderr, "writing png:", err)
+			os.Exit(1)
+		}
+	}
+	if *gifPath != "" {
+		if err := writeGIF(*gifPath, removedGrid, waves, *cellScale, *gifDelay); err != nil {
+			fmt.Fprintln(os.Stderr, "writing gif:", err)
+			os.Exit(1)
+		}
+	}
 }
@@ -2,109 +2,693 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"net"
 	"os"
+	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 type freshRange struct {
 	start int
 	end   int
 	raw   string
+	// line is the input line number the range was read from, used when reporting problems
+	line int
 }
 
+// Interval is an inclusive range of ids
+type Interval struct {
+	Start int
+	End   int
+}
+
+// IntervalSet is a sorted list of disjoint intervals, intervals that overlap or touch are always merged
+// so a lookup only ever has one candidate interval which can be found by binary search.
+type IntervalSet struct {
+	intervals []Interval
+}
+
+// IntervalTree holds fresh ranges that can be added and removed one at a time, keeping the total
+// fresh count and membership answerable after every change without rebuilding anything.
+// Overlapping ranges are kept separately so removing one leaves the ids still covered by another fresh.
+type IntervalTree struct {
+	root *treeNode
+	// ranges counts how many times each range has been inserted, so only inserted ranges can be deleted
+	ranges map[Interval]int
+}
+
+// treeNode covers the ids from lo to hi of its parent's split, children are only created when a
+// range partly covers the node so the tree stays small even though it spans every possible id
+type treeNode struct {
+	left  *treeNode
+	right *treeNode
+	// cover is the number of ranges covering this node's whole span
+	cover int
+	// covered is how many ids in the span at least one range covers
+	covered int
+}
+
+// the tree spans every id from treeMin to treeMax, chosen so the span's size still fits in an int
+const (
+	treeMin = -(1 << 61)
+	treeMax = 1<<61 - 1
+)
+
 var freshRanges []freshRange
 var ingredients []int
 
+// malformedLines holds lines that were neither a range nor an ingredient, reported by -validate
+var malformedLines []string
+
+var (
+	stream     = flag.Bool("stream", false, "after the ranges, answer each ingredient id on stdin as it arrives")
+	socketPath = flag.String("socket", "", "after reading the ranges from stdin, answer ingredient ids sent to this unix socket")
+	report     = flag.Bool("report", false, "list every ingredient with the ranges it matched, followed by range usage statistics")
+	validate   = flag.Bool("validate", false, "report inverted, duplicated and fully contained ranges with their line numbers")
+	scriptPath = flag.String("script", "", "run a script of +a-b (add range), -a-b (remove range) and ?id (lookup) commands against the input ranges")
+
+	// input is kept so streaming mode can carry on reading ingredients after init has read the ranges
+	input *bufio.Scanner
+)
+
 func init() {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
+	flag.Parse()
+	streaming := *stream || *socketPath != ""
+	input = bufio.NewScanner(os.Stdin)
+	lineNumber := 0
+	for input.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(input.Text())
 		if line == "" {
+			// the blank line ends the ranges, when streaming the ingredients are left unread for main
+			if streaming {
+				break
+			}
 			continue
 		}
-		if strings.Contains(line, "-") {
-			parts := strings.Split(line, "-")
+		if start, end, ok := parseRange(line); ok {
 			freshRanges = append(freshRanges, freshRange{
-				start: aToIIgnoreError(parts[0]),
-				end:   aToIIgnoreError(parts[1]),
+				start: start,
+				end:   end,
 				raw:   line,
+				line:  lineNumber,
 			})
-			continue
+		} else if ingredient, err := strconv.Atoi(line); err == nil {
+			ingredients = append(ingredients, ingredient)
 		} else {
-			ingredients = append(ingredients, aToIIgnoreError(line))
+			malformedLines = append(malformedLines, fmt.Sprintf("line %d: %q is neither a range nor an ingredient", lineNumber, line))
 		}
 	}
-	sort.Slice(freshRanges, func(i, j int) bool {
+	// stable so ranges with the same start stay in input order
+	sort.SliceStable(freshRanges, func(i, j int) bool {
 		return freshRanges[i].start < freshRanges[j].start
 	})
+	if len(freshRanges) == 0 {
+		fmt.Fprintln(os.Stderr, "no fresh ranges in the input, every ingredient is spoiled")
+	}
 }
 
-func aToIIgnoreError(s string) int {
-	result, _ := strconv.Atoi(s)
-	return result
+// parseRange splits "start-end" where either end may be negative, e.g. "3-5", "-5-3" or "-5--2".
+// The separator is the first '-' after the first character so a leading minus sign stays with the start.
+func parseRange(line string) (int, int, bool) {
+	if line == "" {
+		return 0, 0, false
+	}
+	separator := strings.Index(line[1:], "-")
+	if separator < 0 {
+		return 0, 0, false
+	}
+	separator++
+	start, startErr := strconv.Atoi(strings.TrimSpace(line[:separator]))
+	end, endErr := strconv.Atoi(strings.TrimSpace(line[separator+1:]))
+	if startErr != nil || endErr != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// validateRanges describes every range problem found, in range start order:
+// inverted ranges (start after end, these are ignored), exact duplicates of an earlier range
+// and ranges fully inside another one, which are harmless but usually a sign of a typo
+func validateRanges() []string {
+	problems := append([]string{}, malformedLines...)
+
+	valid := []freshRange{}
+	for _, fr := range freshRanges {
+		if fr.start > fr.end {
+			problems = append(problems, fmt.Sprintf("line %d: %s is inverted, start is after end", fr.line, fr.raw))
+			continue
+		}
+		valid = append(valid, fr)
+	}
+
+	// sort widest first for equal starts, then any range ending at or before the furthest end seen so far is inside it
+	sort.SliceStable(valid, func(i, j int) bool {
+		if valid[i].start != valid[j].start {
+			return valid[i].start < valid[j].start
+		}
+		return valid[i].end > valid[j].end
+	})
+	widest := -1
+	for i, fr := range valid {
+		// duplicates end up next to each other, report those rather than them being inside a wider range
+		if i > 0 && valid[i-1].start == fr.start && valid[i-1].end == fr.end {
+			problems = append(problems, fmt.Sprintf("line %d: %s duplicates line %d", fr.line, fr.raw, valid[i-1].line))
+			continue
+		}
+		if widest >= 0 {
+			outer := valid[widest]
+			if fr.end <= outer.end {
+				problems = append(problems, fmt.Sprintf("line %d: %s is fully contained in %s on line %d", fr.line, fr.raw, outer.raw, outer.line))
+				continue
+			}
+		}
+		widest = i
+	}
+	return problems
 }
 
 func (fr freshRange) String() string {
 	return fmt.Sprintf("Fresh range from %d to %d (%s)", fr.start, fr.end, fr.raw)
 }
 
-func partOne() int {
-	freshCount := 0
-	for _, ingredient := range ingredients {
-		for _, fr := range freshRanges {
-			if ingredient >= fr.start && ingredient <= fr.end {
-				// fmt.Println(ingredient, "is fresh")
-				freshCount++
-				// break to prevent double counting if an ingredient falls into multiple ranges
-				break
-			}
+// NewIntervalSet builds a set from intervals in any order, merging them in one pass after sorting
+func NewIntervalSet(intervals ...Interval) *IntervalSet {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, iv := range intervals {
+		if iv.Start <= iv.End {
+			sorted = append(sorted, iv)
 		}
 	}
-	return freshCount
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	return &IntervalSet{intervals: mergeSorted(sorted)}
 }
 
-// Can't brute force this one... need to merge ranges first
-// sort the slice (already sorted in init)
-// then iterate through, merging overlapping or contiguous ranges
+// mergeSorted merges overlapping or contiguous intervals of a slice sorted by start
 // for example:
 // [1-3], [2-4], [6-8], [7-10]
 // becomes
 // [1-4], [6-10]
-// then we can just sum the lengths of the merged ranges using (end - start + 1). The +1 is because the ranges are inclusive
-func partTwo() int {
-	mergedRanges := []freshRange{}
-	result := 0
-	// Since slice is sorted this will be the smallest start value
-	current := freshRanges[0]
-	for i := 1; i < len(freshRanges); i++ {
-		next := freshRanges[i]
-		// example: curr: = [1-3] next = [2-4]
-		// 2 (next.start) <= 3 (current.end) + 1
+func mergeSorted(sorted []Interval) []Interval {
+	merged := []Interval{}
+	for _, next := range sorted {
+		// example: last = [1-3] next = [2-4]
+		// 2 (next.Start) <= 3 (last.End)
 		// so [1-3], [2-4] becomes [1-4]
-		if next.start <= current.end+1 {
-			// overlapping or contiguous ranges, merge them
-			if next.end > current.end {
-				current.end = next.end
+		if len(merged) > 0 && touches(merged[len(merged)-1], next) {
+			last := &merged[len(merged)-1]
+			last.End = max(last.End, next.End)
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
+// touches reports whether b, starting at or after a does, overlaps a or starts right after it.
+// It compares without adding 1 to a.End, which would wrap around for a range ending at math.MaxInt.
+func touches(a, b Interval) bool {
+	return b.Start <= a.End || b.Start-a.End == 1
+}
+
+// Intervals returns the merged intervals in order
+func (s *IntervalSet) Intervals() []Interval {
+	return s.intervals
+}
+
+// find returns the index of the first interval that ends at or after id, len(intervals) if there isn't one
+func (s *IntervalSet) find(id int) int {
+	return sort.Search(len(s.intervals), func(i int) bool {
+		return s.intervals[i].End >= id
+	})
+}
+
+// Find returns the interval containing id in O(log n)
+func (s *IntervalSet) Find(id int) (Interval, bool) {
+	i := s.find(id)
+	if i < len(s.intervals) && s.intervals[i].Start <= id {
+		return s.intervals[i], true
+	}
+	return Interval{}, false
+}
+
+// Contains reports whether id is in the set in O(log n)
+func (s *IntervalSet) Contains(id int) bool {
+	_, ok := s.Find(id)
+	return ok
+}
+
+// Insert adds an interval, merging it with any it overlaps or touches.
+// The affected intervals are found by binary search and replaced by a single merged one.
+func (s *IntervalSet) Insert(iv Interval) {
+	if iv.Start > iv.End {
+		return
+	}
+	// first interval that could merge with iv, one ending at or after iv.Start or right before it
+	first := s.find(iv.Start)
+	if first > 0 && touches(s.intervals[first-1], iv) {
+		first--
+	}
+	last := first
+	for last < len(s.intervals) && touches(iv, s.intervals[last]) {
+		iv.Start = min(iv.Start, s.intervals[last].Start)
+		iv.End = max(iv.End, s.intervals[last].End)
+		last++
+	}
+	s.intervals = append(s.intervals[:first], append([]Interval{iv}, s.intervals[last:]...)...)
+}
+
+// Union returns the ids in either set
+func (s *IntervalSet) Union(other *IntervalSet) *IntervalSet {
+	combined := append(append([]Interval{}, s.intervals...), other.intervals...)
+	return NewIntervalSet(combined...)
+}
+
+// Intersection returns the ids in both sets by walking the two sorted lists together
+func (s *IntervalSet) Intersection(other *IntervalSet) *IntervalSet {
+	result := []Interval{}
+	i, j := 0, 0
+	for i < len(s.intervals) && j < len(other.intervals) {
+		a, b := s.intervals[i], other.intervals[j]
+		start, end := max(a.Start, b.Start), min(a.End, b.End)
+		if start <= end {
+			result = append(result, Interval{start, end})
+		}
+		// move past whichever interval finishes first, the other may still overlap the next one
+		if a.End < b.End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return &IntervalSet{intervals: result}
+}
+
+// Subtract returns the ids in s that are not in other
+func (s *IntervalSet) Subtract(other *IntervalSet) *IntervalSet {
+	result := []Interval{}
+	j := 0
+	for _, iv := range s.intervals {
+		start := iv.Start
+		// skip the intervals of other that finish before this one starts
+		for j < len(other.intervals) && other.intervals[j].End < start {
+			j++
+		}
+		// cut out each interval of other that overlaps, keeping the piece before it
+		k := j
+		covered := false
+		for k < len(other.intervals) && other.intervals[k].Start <= iv.End {
+			if other.intervals[k].Start > start {
+				result = append(result, Interval{start, other.intervals[k].Start - 1})
+			}
+			if other.intervals[k].End >= iv.End {
+				// the rest of iv is cut out, and other's End+1 could wrap past math.MaxInt
+				start = iv.End
+				covered = true
+				break
+			}
+			start = max(start, other.intervals[k].End+1)
+			k++
+		}
+		if !covered && start <= iv.End {
+			result = append(result, Interval{start, iv.End})
+		}
+	}
+	return &IntervalSet{intervals: result}
+}
+
+// Len is the total number of ids in the set, (end - start + 1) per interval as they are inclusive.
+// Sets covering most of the signed id range hold more ids than an int can count, that is an error rather than wrapping.
+func (s *IntervalSet) Len() (int, error) {
+	var total uint64
+	for _, iv := range s.intervals {
+		// End - Start wraps for intervals wider than math.MaxInt, read as a uint64 it is still exact
+		size := uint64(iv.End-iv.Start) + 1
+		var carry uint64
+		total, carry = bits.Add64(total, size, 0)
+		if size == 0 || carry != 0 || total > math.MaxInt {
+			return 0, errors.New("the number of fresh ids overflows an int")
+		}
+	}
+	return int(total), nil
+}
+
+func freshSet() *IntervalSet {
+	intervals := make([]Interval, 0, len(freshRanges))
+	for _, fr := range freshRanges {
+		intervals = append(intervals, Interval{fr.start, fr.end})
+	}
+	return NewIntervalSet(intervals...)
+}
+
+// partOne looks each ingredient up in the merged fresh ranges by binary search,
+// merging also means an ingredient in several ranges can't be double counted
+func partOne() int {
+	fresh := freshSet()
+	freshCount := 0
+	for _, ingredient := range ingredients {
+		if fresh.Contains(ingredient) {
+			freshCount++
+		}
+	}
+	return freshCount
+}
+
+// Can't brute force this one... need to merge ranges first,
+// then we can just sum the lengths of the merged ranges
+func partTwo() (int, error) {
+	return freshSet().Len()
+}
+
+// answerLookups reads one ingredient id per line and answers each as soon as it is read with
+// "id fresh start-end" giving the merged range it is in, or "id spoiled -".
+// Only the current line is held in memory so the ingredient stream can be as long as it likes.
+func answerLookups(fresh *IntervalSet, ids *bufio.Scanner, out io.Writer) error {
+	writer := bufio.NewWriter(out)
+	for ids.Scan() {
+		line := strings.TrimSpace(ids.Text())
+		if line == "" {
+			continue
+		}
+		id, err := strconv.Atoi(line)
+		if err != nil {
+			fmt.Fprintf(writer, "%s invalid -\n", line)
+		} else if iv, ok := fresh.Find(id); ok {
+			fmt.Fprintf(writer, "%d fresh %d-%d\n", id, iv.Start, iv.End)
+		} else {
+			fmt.Fprintf(writer, "%d spoiled -\n", id)
+		}
+		// flush every answer so a client waiting on a reply isn't left hanging
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return ids.Err()
+}
+
+// serveSocket answers lookups from every client connecting to the unix socket, the merged ranges
+// are only read so clients can be served concurrently. It runs until SIGINT or SIGTERM,
+// closing the listener then also removes the socket file.
+func serveSocket(fresh *IntervalSet, path string) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
 			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := answerLookups(fresh, bufio.NewScanner(conn), conn); err != nil {
+				fmt.Fprintln(os.Stderr, "client:", err)
+			}
+		}()
+	}
+}
+
+// removeStaleSocket deletes a socket file left behind by a server that was killed.
+// Anything that isn't a socket, or a socket another server is still answering on, is left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and isn't a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is already being served", path)
+	}
+	return os.Remove(path)
+}
+
+// matchingRanges returns the index of every fresh range containing the ingredient, not just the first.
+// freshRanges is sorted by start so only the ranges starting at or before the ingredient need checking.
+func matchingRanges(ingredient int) []int {
+	candidates := sort.Search(len(freshRanges), func(i int) bool {
+		return freshRanges[i].start > ingredient
+	})
+	matches := []int{}
+	for i := 0; i < candidates; i++ {
+		if freshRanges[i].end >= ingredient {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// printReport lists each ingredient in input order with the raw text of all the ranges it fell in,
+// then summarises how often each range was used
+func printReport() {
+	usage := make([]int, len(freshRanges))
+	freshCount := 0
+	for _, ingredient := range ingredients {
+		matches := matchingRanges(ingredient)
+		if len(matches) == 0 {
+			fmt.Printf("%d spoiled\n", ingredient)
+			continue
+		}
+		freshCount++
+		raws := []string{}
+		for _, idx := range matches {
+			usage[idx]++
+			raws = append(raws, freshRanges[idx].raw)
+		}
+		fmt.Printf("%d fresh %s\n", ingredient, strings.Join(raws, " "))
+	}
+
+	fmt.Printf("%d fresh, %d spoiled\n", freshCount, len(ingredients)-freshCount)
+	mostUsed := -1
+	unused := []string{}
+	for idx, count := range usage {
+		if count == 0 {
+			unused = append(unused, freshRanges[idx].raw)
+		}
+		if count > 0 && (mostUsed == -1 || count > usage[mostUsed]) {
+			mostUsed = idx
+		}
+	}
+	if mostUsed >= 0 {
+		fmt.Printf("most used range: %s (%d ingredients)\n", freshRanges[mostUsed].raw, usage[mostUsed])
+	}
+	fmt.Printf("%d ranges matched nothing: %s\n", len(unused), strings.Join(unused, " "))
+}
+
+func NewIntervalTree() *IntervalTree {
+	return &IntervalTree{root: &treeNode{}, ranges: map[Interval]int{}}
+}
+
+// Insert adds a fresh range in O(log span), ranges reaching outside treeMin to treeMax are rejected
+func (t *IntervalTree) Insert(iv Interval) error {
+	if iv.Start > iv.End {
+		return nil
+	}
+	if iv.Start < treeMin || iv.End > treeMax {
+		return fmt.Errorf("range %d-%d is outside the ids the tree can hold, %d to %d", iv.Start, iv.End, treeMin, treeMax)
+	}
+	t.ranges[iv]++
+	t.root.update(treeMin, treeMax, iv, 1)
+	return nil
+}
+
+// Delete removes one copy of a previously inserted range, returning false if it was never inserted
+func (t *IntervalTree) Delete(iv Interval) bool {
+	if t.ranges[iv] == 0 {
+		return false
+	}
+	t.ranges[iv]--
+	if t.ranges[iv] == 0 {
+		delete(t.ranges, iv)
+	}
+	t.root.update(treeMin, treeMax, iv, -1)
+	return true
+}
+
+// Len is the number of fresh ids, the same as partTwo on the current ranges
+func (t *IntervalTree) Len() int {
+	return t.root.covered
+}
+
+// Contains walks down towards id, it is fresh as soon as a node on the way is wholly covered by a range
+func (t *IntervalTree) Contains(id int) bool {
+	node, lo, hi := t.root, treeMin, treeMax
+	for node != nil {
+		if node.cover > 0 {
+			return true
+		}
+		mid := lo + (hi-lo)/2
+		if id <= mid {
+			node, hi = node.left, mid
 		} else {
-			// no overlap, add current to merged and move to next one
-			mergedRanges = append(mergedRanges, current)
-			current = next
+			node, lo = node.right, mid+1
+		}
+	}
+	return false
+}
+
+// update adds delta to the cover of every node the range fully spans, splitting nodes it only partly
+// covers, then recalculates covered on the way back up
+func (n *treeNode) update(lo, hi int, iv Interval, delta int) {
+	if iv.End < lo || iv.Start > hi {
+		return
+	}
+	if iv.Start <= lo && hi <= iv.End {
+		n.cover += delta
+	} else {
+		if n.left == nil {
+			n.left, n.right = &treeNode{}, &treeNode{}
 		}
+		mid := lo + (hi-lo)/2
+		n.left.update(lo, mid, iv, delta)
+		n.right.update(mid+1, hi, iv, delta)
 	}
-	// add the last range
-	mergedRanges = append(mergedRanges, current)
 
-	for _, mergedRanges := range mergedRanges {
-		result += (mergedRanges.end - mergedRanges.start + 1)
+	switch {
+	case n.cover > 0:
+		n.covered = hi - lo + 1
+	case n.left != nil:
+		n.covered = n.left.covered + n.right.covered
+	default:
+		n.covered = 0
 	}
-	return result
+}
+
+// runScript applies each command in turn to a tree seeded with the input's fresh ranges:
+// "+a-b" adds a range and "-a-b" removes one, both printing the new fresh total, "?id" looks an id up
+func runScript(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tree := NewIntervalTree()
+	for _, fr := range freshRanges {
+		if err := tree.Insert(Interval{fr.start, fr.end}); err != nil {
+			return fmt.Errorf("input line %d: %w", fr.line, err)
+		}
+	}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" {
+			continue
+		}
+		if len(command) < 2 {
+			return fmt.Errorf("line %d: %q is missing its argument, expected +a-b, -a-b or ?id", lineNumber, command)
+		}
+		argument := command[1:]
+		switch command[0] {
+		case '+', '-':
+			start, end, ok := parseRange(argument)
+			if !ok || start > end {
+				return fmt.Errorf("line %d: %q is not a valid range", lineNumber, argument)
+			}
+			if command[0] == '+' {
+				if err := tree.Insert(Interval{start, end}); err != nil {
+					return fmt.Errorf("line %d: %w", lineNumber, err)
+				}
+			} else if !tree.Delete(Interval{start, end}) {
+				fmt.Printf("%s: range was never added\n", command)
+				continue
+			}
+			fmt.Printf("%s: %d fresh\n", command, tree.Len())
+		case '?':
+			id, err := strconv.Atoi(argument)
+			if err != nil {
+				return fmt.Errorf("line %d: %q is not an ingredient id", lineNumber, argument)
+			}
+			if id < treeMin || id > treeMax {
+				return fmt.Errorf("line %d: id %d is outside the ids the tree can hold, %d to %d", lineNumber, id, treeMin, treeMax)
+			}
+			if tree.Contains(id) {
+				fmt.Printf("%s: fresh\n", command)
+			} else {
+				fmt.Printf("%s: spoiled\n", command)
+			}
+		default:
+			return fmt.Errorf("line %d: unknown command %q, expected +a-b, -a-b or ?id", lineNumber, command)
+		}
+	}
+	return scanner.Err()
 }
 
 func main() {
+	if *scriptPath != "" {
+		if err := runScript(*scriptPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *validate {
+		problems := validateRanges()
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		fmt.Printf("%d ranges checked, %d problems\n", len(freshRanges), len(problems))
+		return
+	}
+	if *report {
+		printReport()
+		return
+	}
+	if *socketPath != "" {
+		if err := serveSocket(freshSet(), *socketPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *stream {
+		if err := answerLookups(freshSet(), input, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	println("Part One:", partOne())
-	println("Part Two:", partTwo())
+	freshIDs, err := partTwo()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "part two:", err)
+		os.Exit(1)
+	}
+	println("Part Two:", freshIDs)
 }
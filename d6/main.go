@@ -2,107 +2,460 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
 	"os"
-	"strconv"
 	"strings"
 )
 
 var (
-	lines      []string
-	dataLines  []string
-	operations []string
+	lines    []string
+	problems []problem
+
+	arithmetic = flag.String("arithmetic", "int", "number type: int (64-bit, wraps on overflow), checked (64-bit, stops at the first problem that overflows) or big (arbitrary precision)")
+	tabWidth   = flag.Int("tab-width", 8, "columns between tab stops when expanding tabs in the worksheet")
+	render     = flag.String("render", "", "print the worksheet rewritten in another layout instead of solving it: vertical (each problem's row numbers laid out down the columns) or horizontal (its column numbers laid out across the rows)")
+	fold       = flag.String("fold", "left", "order each problem's numbers are combined in: left ((a op b) op c) or right (a op (b op c))")
 )
 
-// calculateSum applies a sequence of operations column-wise and returns the summed result.
-// For each list of numbers in list, the corresponding operator in
-// `operators` determines how they are reduced:
-//   - "*" multiplies all numbers together
-//   - "+" sums all numbers together
-func calculateSum(listOfNums [][]int, operators []string) int {
-	results := []int{}
-	for i, nums := range listOfNums {
-		if i < len(operators) {
-			operator := operators[i]
-			if operator == "*" {
-				// Multiply all numbers in the slice together
-				if len(nums) > 0 {
-					product := nums[0]
-					for _, num := range nums[1:] {
-						product *= num
-					}
-					results = append(results, product)
-				}
-			} else if operator == "+" {
-				sum := 0
-				for _, num := range nums {
-					sum += num
-				}
-				results = append(results, sum)
+// errOverflow marks a result that doesn't fit in 64 bits when running with -arithmetic checked
+var errOverflow = errors.New("overflows int64, rerun with -arithmetic big")
+
+var (
+	minInt64 = big.NewInt(math.MinInt64)
+	wordSize = new(big.Int).Lsh(big.NewInt(1), 64)
+	one      = big.NewInt(1)
+	maxPower = big.NewInt(64)
+)
+
+// fit brings a value back into the number type chosen with -arithmetic:
+// big leaves it alone, checked rejects anything outside int64 and int wraps it around the way int64 arithmetic does
+func fit(x *big.Int) (*big.Int, error) {
+	switch *arithmetic {
+	case "big":
+		return x, nil
+	case "checked":
+		if !x.IsInt64() {
+			return nil, errOverflow
+		}
+		return x, nil
+	}
+	if x.IsInt64() {
+		return x, nil
+	}
+	wrapped := new(big.Int).Sub(x, minInt64)
+	wrapped.Mod(wrapped, wordSize)
+	return wrapped.Add(wrapped, minInt64), nil
+}
+
+// operators maps each worksheet symbol to how it combines two numbers:
+//   - "+" adds, "*" multiplies, "-" subtracts and "/" divides (whole numbers only)
+//   - "min" and "max" keep the smaller or larger number
+//   - "^" raises to the power
+//   - "||" concatenates the digits, e.g. 12 || 34 is 1234
+//
+// results are exact, fit then applies the -arithmetic mode after every step
+var operators = map[string]func(a, b *big.Int) (*big.Int, error){
+	"+": func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Add(a, b), nil },
+	"*": func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Mul(a, b), nil },
+	"-": func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Sub(a, b), nil },
+	"/": func(a, b *big.Int) (*big.Int, error) {
+		if b.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		// Quo truncates towards zero like int division does
+		return new(big.Int).Quo(a, b), nil
+	},
+	"min": func(a, b *big.Int) (*big.Int, error) {
+		if a.Cmp(b) <= 0 {
+			return a, nil
+		}
+		return b, nil
+	},
+	"max": func(a, b *big.Int) (*big.Int, error) {
+		if a.Cmp(b) >= 0 {
+			return a, nil
+		}
+		return b, nil
+	},
+	"^": func(a, b *big.Int) (*big.Int, error) {
+		if b.Sign() < 0 {
+			return nil, fmt.Errorf("negative exponent %s", b)
+		}
+		// anything bigger than 1 raised past 64 can't fit in 64 bits,
+		// so don't build the huge number just to reject it or wrap it
+		if *arithmetic != "big" && a.CmpAbs(one) > 0 && b.Cmp(maxPower) > 0 {
+			if *arithmetic == "checked" {
+				return nil, errOverflow
 			}
+			return new(big.Int).Exp(a, b, wordSize), nil
+		}
+		return new(big.Int).Exp(a, b, nil), nil
+	},
+	"||": func(a, b *big.Int) (*big.Int, error) {
+		if b.Sign() < 0 {
+			return nil, fmt.Errorf("can't concatenate negative number %s", b)
+		}
+		result, _ := new(big.Int).SetString(a.String()+b.String(), 10)
+		return result, nil
+	},
+}
+
+// reduce combines a problem's numbers with its operator, left to right by default
+// e.g. 100 - 20 - 5 is (100 - 20) - 5 = 75 folding left and 100 - (20 - 5) = 85 folding right
+func reduce(nums []*big.Int, symbol string) (*big.Int, error) {
+	values := make([]*big.Int, len(nums))
+	for i, num := range nums {
+		var err error
+		if values[i], err = fit(num); err != nil {
+			return nil, fmt.Errorf("number %s %w", num, err)
 		}
 	}
 
-	total := 0
-	for _, result := range results {
-		total += result
+	apply := func(a, b *big.Int) (*big.Int, error) {
+		result, err := operators[symbol](a, b)
+		if err != nil {
+			return nil, err
+		}
+		return fit(result)
 	}
-	return total
+
+	if *fold == "right" {
+		result := values[len(values)-1]
+		for i := len(values) - 2; i >= 0; i-- {
+			var err error
+			if result, err = apply(values[i], result); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	result := values[0]
+	for _, value := range values[1:] {
+		var err error
+		if result, err = apply(result, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-func reverseString(s string) string {
-	runes := []rune(s)
-	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
-		runes[i], runes[j] = runes[j], runes[i]
+// calculateSum reduces each problem's numbers, as picked out by numbers, with its operator
+// (see operators) and returns the summed result
+func calculateSum(problems []problem, numbers func(problem) ([]*big.Int, error)) (*big.Int, error) {
+	total := new(big.Int)
+	for i, p := range problems {
+		nums, err := numbers(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(nums) == 0 {
+			continue
+		}
+		result, err := reduce(nums, p.operator)
+		if err != nil {
+			return nil, fmt.Errorf("problem %d at %s (%s): %w", i+1, p.span(), p.operator, err)
+		}
+		if total, err = fit(new(big.Int).Add(total, result)); err != nil {
+			return nil, fmt.Errorf("sum up to problem %d at %s: %w", i+1, p.span(), err)
+		}
 	}
-	return string(runes)
+	return total, nil
 }
 
-func init() {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// problem is one worksheet problem: the columns it spans, its operator and its numbers read both ways
+type problem struct {
+	start, end int // columns [start, end) of the padded worksheet
+	operator   string
+	rows       []*big.Int // numbers written across each row, top to bottom, for part one
+	columns    []*big.Int // numbers written down each column, right to left, for part two
+
+	// a worksheet laid out for one part often can't be read the other way,
+	// so a bad number is only reported when its part is solved
+	rowsErr, columnsErr error
+}
+
+func (p problem) rowNumbers() ([]*big.Int, error) {
+	return p.rows, p.rowsErr
+}
+
+func (p problem) columnNumbers() ([]*big.Int, error) {
+	return p.columns, p.columnsErr
+}
+
+// span describes the problem's columns, counting from 1 like an editor does
+func (p problem) span() string {
+	if p.end-p.start == 1 {
+		return fmt.Sprintf("column %d", p.start+1)
 	}
+	return fmt.Sprintf("columns %d-%d", p.start+1, p.end)
+}
 
-	dataLines = lines[:len(lines)-1]
-	operations = strings.Fields(lines[len(lines)-1])
+// expandTabs replaces each tab with spaces up to the next tab stop so columns line up the way they're displayed
+func expandTabs(line string, width int) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var expanded strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			pad := width - col%width
+			expanded.WriteString(strings.Repeat(" ", pad))
+			col += pad
+			continue
+		}
+		expanded.WriteRune(r)
+		col++
+	}
+	return expanded.String()
 }
 
-// transforms horizontal rows of integers into vertical columns.
-// for example, given input and input lines of ["123 328 51", "45 64 387", "6 98 215"],
-// it would return [[123 45 6], [328 64 98], [51 387 215]]
-// These can then be processed column-wise but it ignores the formatting of number in the input
-func partOne() int {
-	numbersHorizontal := [][]int{}
-	for _, line := range dataLines {
-		row := []int{}
-		for _, num := range strings.Fields(line) {
-			row = append(row, aToIIgnoreError(num))
+// parseWorksheet lays the worksheet out as a grid (tabs expanded, ragged rows padded with spaces),
+// finds the problems between the fully blank columns and reads each problem's operator and numbers.
+// The last non-blank line holds the operators, one under each problem, for example:
+//
+//	123 328  51 64
+//	 45 64  387 23
+//	  6 98  215 314
+//	*   +   *   +
+//
+// is four problems spanning columns 1-3, 5-7, 9-11 and 13-15
+func parseWorksheet(lines []string) ([]problem, error) {
+	grid := []string{}
+	for _, line := range lines {
+		grid = append(grid, expandTabs(strings.TrimRight(line, "\r"), *tabWidth))
+	}
+	for len(grid) > 0 && strings.TrimSpace(grid[len(grid)-1]) == "" {
+		grid = grid[:len(grid)-1]
+	}
+	if len(grid) < 2 {
+		return nil, errors.New("worksheet needs at least one row of numbers and a row of operators")
+	}
+
+	width := 0
+	for _, line := range grid {
+		width = max(width, len(line))
+	}
+	for i, line := range grid {
+		grid[i] = line + strings.Repeat(" ", width-len(line))
+	}
+	rows, operatorLine := grid[:len(grid)-1], grid[len(grid)-1]
+
+	blank := make([]bool, width)
+	for col := range width {
+		blank[col] = true
+		for _, line := range grid {
+			if line[col] != ' ' {
+				blank[col] = false
+				break
+			}
+		}
+	}
+
+	problems := []problem{}
+	for col := 0; col < width; {
+		if blank[col] {
+			col++
+			continue
+		}
+		p := problem{start: col}
+		for col < width && !blank[col] {
+			col++
+		}
+		p.end = col
+
+		var err error
+		if p.operator, err = parseOperator(operatorLine, p.start, p.end); err != nil {
+			return nil, err
+		}
+		for r, line := range rows {
+			field := strings.TrimSpace(line[p.start:p.end])
+			if field == "" {
+				continue
+			}
+			num, ok := new(big.Int).SetString(field, 10)
+			if !ok {
+				p.rowsErr = fmt.Errorf("row %d %s: %q is not a number", r+1, p.span(), field)
+				break
+			}
+			p.rows = append(p.rows, num)
+		}
+		for c := p.end - 1; c >= p.start; c-- {
+			// rows can align their numbers differently, so a column's digits may have gaps between them
+			var digits strings.Builder
+			for _, line := range rows {
+				if line[c] != ' ' {
+					digits.WriteByte(line[c])
+				}
+			}
+			field := digits.String()
+			if field == "" {
+				continue
+			}
+			num, ok := new(big.Int).SetString(field, 10)
+			if !ok || field[0] == '-' || field[0] == '+' {
+				p.columnsErr = fmt.Errorf("column %d: %q is not a number", c+1, field)
+				break
+			}
+			p.columns = append(p.columns, num)
 		}
-		numbersHorizontal = append(numbersHorizontal, row)
+		problems = append(problems, p)
 	}
+	return problems, nil
+}
 
-	numColumns := len(numbersHorizontal[0])
-	numbersVertical := [][]int{}
+// parseOperator reads the operator under columns [start, end), reporting a missing or unknown operator
+// with a marker under its column, for example:
+//
+//	unknown operator "%" at column 9
+//	*   +   %   +
+//	        ^
+func parseOperator(line string, start, end int) (string, error) {
+	marked := func(format string, col int, args ...any) error {
+		return fmt.Errorf(format+"\n%s\n%s^", append(args, strings.TrimRight(line, " "), strings.Repeat(" ", col))...)
+	}
+	symbols := strings.Fields(line[start:end])
+	if len(symbols) == 0 {
+		return "", marked("no operator under %s", start, problem{start: start, end: end}.span())
+	}
+	offset := start + strings.Index(line[start:end], symbols[0])
+	if len(symbols) > 1 {
+		return "", marked("more than one operator under %s", offset, problem{start: start, end: end}.span())
+	}
+	if _, ok := operators[symbols[0]]; !ok {
+		return "", marked("unknown operator %q at column %d", offset, symbols[0], offset+1)
+	}
+	return symbols[0], nil
+}
 
-	for index := 0; index < numColumns; index++ {
-		column := []int{}
-		for _, row := range numbersHorizontal {
-			column = append(column, row[index])
+// renderHorizontal lays the problems out the way the rows are read by partOne: one number per line,
+// right aligned within the problem's columns, with the operator under its first column
+func renderHorizontal(problems []problem) string {
+	height := 0
+	for _, p := range problems {
+		height = max(height, len(p.rows))
+	}
+	lines := make([]strings.Builder, height+1)
+	for i, p := range problems {
+		width := len(p.operator)
+		for _, num := range p.rows {
+			width = max(width, len(num.String()))
+		}
+		for r := range height {
+			if i > 0 {
+				lines[r].WriteByte(' ')
+			}
+			field := ""
+			if r < len(p.rows) {
+				field = p.rows[r].String()
+			}
+			fmt.Fprintf(&lines[r], "%*s", width, field)
+		}
+		if i > 0 {
+			lines[height].WriteByte(' ')
+		}
+		fmt.Fprintf(&lines[height], "%-*s", width, p.operator)
+	}
+	return joinTrimmed(lines)
+}
+
+// renderVertical lays the problems out the cephalopod way the columns are read by partTwo: the first number
+// goes down the problem's right hand column, the next one down the column to its left and so on,
+// each bottom aligned, with the operator under the problem's first column.
+// A column can't hold a sign, so negative numbers can't be rendered.
+func renderVertical(problems []problem) (string, error) {
+	height := 0
+	for i, p := range problems {
+		for _, num := range p.columns {
+			if num.Sign() < 0 {
+				return "", fmt.Errorf("problem %d: can't write negative number %s down a column", i+1, num)
+			}
+			height = max(height, len(num.String()))
+		}
+	}
+	lines := make([]strings.Builder, height+1)
+	for i, p := range problems {
+		width := max(len(p.columns), len(p.operator))
+		for r := range height {
+			if i > 0 {
+				lines[r].WriteByte(' ')
+			}
+			for c := range width {
+				digit := byte(' ')
+				// numbers are read right to left, the first one sits in the last column
+				if k := width - 1 - c; k < len(p.columns) {
+					digits := p.columns[k].String()
+					if offset := r - (height - len(digits)); offset >= 0 {
+						digit = digits[offset]
+					}
+				}
+				lines[r].WriteByte(digit)
+			}
 		}
-		numbersVertical = append(numbersVertical, column)
+		if i > 0 {
+			lines[height].WriteByte(' ')
+		}
+		fmt.Fprintf(&lines[height], "%-*s", width, p.operator)
 	}
+	return joinTrimmed(lines), nil
+}
 
-	return calculateSum(numbersVertical, operations)
+// joinTrimmed joins the rendered lines without their trailing padding, parseWorksheet pads them back out
+func joinTrimmed(lines []strings.Builder) string {
+	var joined strings.Builder
+	for _, line := range lines {
+		joined.WriteString(strings.TrimRight(line.String(), " "))
+		joined.WriteByte('\n')
+	}
+	return joined.String()
 }
 
-func aToIIgnoreError(s string) int {
-	result, _ := strconv.Atoi(s)
-	return result
+// readWorksheet handles the flags and parses the worksheet on stdin.
+// Called from main instead of init, which would have the tests waiting on stdin too.
+func readWorksheet() {
+	flag.Parse()
+	if *fold != "left" && *fold != "right" {
+		fmt.Fprintf(os.Stderr, "fold must be left or right, got %q\n", *fold)
+		os.Exit(1)
+	}
+	if *arithmetic != "int" && *arithmetic != "checked" && *arithmetic != "big" {
+		fmt.Fprintf(os.Stderr, "arithmetic must be int, checked or big, got %q\n", *arithmetic)
+		os.Exit(1)
+	}
+	if *tabWidth < 1 {
+		fmt.Fprintf(os.Stderr, "tab-width must be at least 1, got %d\n", *tabWidth)
+		os.Exit(1)
+	}
+	if *render != "" && *render != "vertical" && *render != "horizontal" {
+		fmt.Fprintf(os.Stderr, "render must be vertical or horizontal, got %q\n", *render)
+		os.Exit(1)
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	var err error
+	problems, err = parseWorksheet(lines)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-// partTwo extracts numbers by reading the input grid column-by-column from right to left.
-// Each column of characters is treated as a vertical string. These strings are reversed
-// per row and stitched together to form integer values. Blank columns indicate separation.
+// partOne reads each problem's numbers across the rows,
+// for example the lines ["123 328 51", "45 64 387", "6 98 215"] give [[123 45 6], [328 64 98], [51 387 215]]
+func partOne() (*big.Int, error) {
+	return calculateSum(problems, problem.rowNumbers)
+}
+
+// partTwo reads each problem's numbers down the columns, right to left.
+// The digits in a column are read top to bottom with the padding spaces ignored.
 //
 // Example: given input lines:
 //
@@ -110,54 +463,57 @@ func aToIIgnoreError(s string) int {
 //	" 45 64  387 23 "
 //	"  6 98  215 314"
 //
-// Reading right-to-left produces groups: [[4, 431, 623], [175, 581, 32], [8, 248, 369], [356, 24, 1]]
-// this is because the final column for example, right to left by column is:
-// 4 (4 from " 314") + 431 (4 from " 64 ") + 23 (3 from " 23 ") + 1 (from "123 ") and then the same for 623
-func partTwo() int {
-	// part 2 says it needs to processed right to left to reverse the operators
-	reversedOperators := make([]string, len(operations))
-	for i, j := 0, len(operations)-1; i < j; i, j = i+1, j-1 {
-		reversedOperators[i], reversedOperators[j] = operations[j], operations[i]
-	}
-
-	numPositions := len(dataLines[0])
-
-	numbers := []int{}
-	numbersLeftToRight := [][]int{}
-
-	for index := 0; index < numPositions; index++ {
-		// Collect characters from each row at this column position (measured from the right)
-		num := ""
-		for _, row := range dataLines {
-			//reverse due to needing right to left
-			reversed := reverseString(row)
-			if index < len(reversed) {
-				// get all the characters in this column for each and build a number from them
-				// may contain spaces at this stage, for example "  4"
-				num += string(reversed[index])
+// the problems are [[356, 24, 1], [8, 248, 369], [175, 581, 32], [4, 431, 623]]
+// e.g. the last problem's right hand column is "  4" giving 4, then "431" and "623"
+func partTwo() (*big.Int, error) {
+	return calculateSum(problems, problem.columnNumbers)
+}
+
+func main() {
+	readWorksheet()
+	if *render == "horizontal" {
+		// the numbers read down the columns become the rows, so part one of the new worksheet is part two of this one
+		for i := range problems {
+			if problems[i].columnsErr != nil {
+				fmt.Fprintln(os.Stderr, problems[i].columnsErr)
+				os.Exit(1)
 			}
+			problems[i].rows = problems[i].columns
 		}
-		cleaned := strings.TrimSpace(num)
-		// cleaned converts the column's vertical string to a number, ignoring spaces
-		// e.g., "  4" → "4" → 4; "431" → 431. Blank columns (only spaces) separate groups.
-		if cleaned == "" {
-			// Empty column → end of group
-			numbersLeftToRight = append(numbersLeftToRight, numbers)
-			numbers = []int{}
-		} else if index == numPositions-1 {
-			// Last column → finalize group
-			numbers = append(numbers, aToIIgnoreError(cleaned))
-			numbersLeftToRight = append(numbersLeftToRight, numbers)
-		} else {
-			numbers = append(numbers, aToIIgnoreError(cleaned))
+		fmt.Print(renderHorizontal(problems))
+		return
+	}
+	if *render == "vertical" {
+		for i := range problems {
+			if problems[i].rowsErr != nil {
+				fmt.Fprintln(os.Stderr, problems[i].rowsErr)
+				os.Exit(1)
+			}
+			problems[i].columns = problems[i].rows
 		}
+		sheet, err := renderVertical(problems)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(sheet)
+		return
+	}
+	// a worksheet laid out for one part may not read the other way, so carry on to the other part
+	failed := false
+	if partOne, err := partOne(); err != nil {
+		fmt.Fprintln(os.Stderr, "part one:", err)
+		failed = true
+	} else {
+		println("Part One:", partOne.String())
+	}
+	if partTwo, err := partTwo(); err != nil {
+		fmt.Fprintln(os.Stderr, "part two:", err)
+		failed = true
+	} else {
+		println("Part Two:", partTwo.String())
+	}
+	if failed {
+		os.Exit(1)
 	}
-
-	return calculateSum(numbersLeftToRight, reversedOperators)
-
-}
-
-func main() {
-	println("Part One:", partOne())
-	println("Part Two:", partTwo())
 }
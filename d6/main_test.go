@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var example = []string{
+	"123 328  51 64 ",
+	" 45 64  387 23 ",
+	"  6 98  215 314",
+	"*   +   *   +  ",
+}
+
+func numbers(values ...int64) []*big.Int {
+	nums := []*big.Int{}
+	for _, v := range values {
+		nums = append(nums, big.NewInt(v))
+	}
+	return nums
+}
+
+func sameNumbers(a, b []*big.Int) bool {
+	return slices.EqualFunc(a, b, func(x, y *big.Int) bool { return x.Cmp(y) == 0 })
+}
+
+func TestParseWorksheetExample(t *testing.T) {
+	expected := []problem{
+		{start: 0, end: 3, operator: "*", rows: numbers(123, 45, 6), columns: numbers(356, 24, 1)},
+		{start: 4, end: 7, operator: "+", rows: numbers(328, 64, 98), columns: numbers(8, 248, 369)},
+		{start: 8, end: 11, operator: "*", rows: numbers(51, 387, 215), columns: numbers(175, 581, 32)},
+		{start: 12, end: 15, operator: "+", rows: numbers(64, 23, 314), columns: numbers(4, 431, 623)},
+	}
+	parsed, err := parseWorksheet(example)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(expected) {
+		t.Fatalf("got %d problems, expected %d", len(parsed), len(expected))
+	}
+	for i, p := range parsed {
+		e := expected[i]
+		if p.start != e.start || p.end != e.end || p.operator != e.operator ||
+			!sameNumbers(p.rows, e.rows) || !sameNumbers(p.columns, e.columns) {
+			t.Errorf("problem %d: got %s %s rows %v columns %v, expected %s %s rows %v columns %v",
+				i+1, p.span(), p.operator, p.rows, p.columns, e.span(), e.operator, e.rows, e.columns)
+		}
+	}
+}
+
+// TestRenderRoundTrip renders random problems in both layouts and checks parsing gives them back.
+// Numbers run up to 30 digits and problems hold 1 to 5 of them, so ragged widths,
+// operators wider than their numbers and values past int64 all come up.
+func TestRenderRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewPCG(6, 2025))
+	symbols := []string{}
+	for symbol := range operators {
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+
+	randomNumbers := func() []*big.Int {
+		nums := make([]*big.Int, 1+random.IntN(5))
+		for i := range nums {
+			digits := make([]byte, 1+random.IntN(30))
+			for d := range digits {
+				digits[d] = byte('0' + random.IntN(10))
+			}
+			nums[i], _ = new(big.Int).SetString(string(digits), 10)
+		}
+		return nums
+	}
+
+	for range 1000 {
+		original := make([]problem, 1+random.IntN(6))
+		for i := range original {
+			original[i] = problem{
+				operator: symbols[random.IntN(len(symbols))],
+				rows:     randomNumbers(),
+				columns:  randomNumbers(),
+			}
+		}
+
+		vertical, err := renderVertical(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, layout := range []struct {
+			name, sheet string
+			numbers     func(problem) ([]*big.Int, error)
+		}{
+			{"horizontal", renderHorizontal(original), problem.rowNumbers},
+			{"vertical", vertical, problem.columnNumbers},
+		} {
+			parsed, err := parseWorksheet(strings.Split(layout.sheet, "\n"))
+			if err != nil {
+				t.Fatalf("%s worksheet: %v\n%s", layout.name, err, layout.sheet)
+			}
+			if len(parsed) != len(original) {
+				t.Fatalf("%s worksheet: got %d problems, expected %d\n%s", layout.name, len(parsed), len(original), layout.sheet)
+			}
+			for i := range parsed {
+				got, err := layout.numbers(parsed[i])
+				expected, _ := layout.numbers(original[i])
+				if err != nil || parsed[i].operator != original[i].operator || !sameNumbers(got, expected) {
+					t.Fatalf("%s worksheet problem %d: got %s %v (%v), expected %s %v\n%s",
+						layout.name, i+1, parsed[i].operator, got, err, original[i].operator, expected, layout.sheet)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderVerticalRejectsNegativeNumbers(t *testing.T) {
+	if _, err := renderVertical([]problem{{operator: "+", columns: numbers(3, -5)}}); err == nil {
+		t.Fatal("expected an error rendering -5 down a column")
+	}
+}